@@ -33,6 +33,39 @@ const docTemplate = `{
                     "fruit"
                 ],
                 "summary": "Gets all fruits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter expression e.g. season eq 'Summer' and name like 'ber'",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Maximum number of fruits to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of fruits to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields to sort on, prefix a field with '-' to sort descending e.g. -created_at,name",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor from the X-Next-Cursor header of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -41,18 +74,44 @@ const docTemplate = `{
                             "items": {
                                 "$ref": "#/definitions/db.Fruit"
                             }
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Links to the next, prev, first and last pages"
+                            },
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "Cursor to get the next page"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Total number of matching fruits"
+                            }
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
                         "schema": {
-                            "$ref": "#/definitions/utils.HTTPError"
+                            "$ref": "#/definitions/utils.Problem"
                         }
                     }
                 }
             },
             "delete": {
-                "description": "Delete all fruit from Database",
+                "description": "Moves all the fruits to the trash, they could be restored until they are purged",
                 "tags": [
                     "fruit"
                 ],
@@ -61,10 +120,16 @@ const docTemplate = `{
                     "204": {
                         "description": "No Content"
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/utils.HTTPError"
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
                         }
                     }
                 }
@@ -95,195 +160,1479 @@ const docTemplate = `{
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
                             "$ref": "#/definitions/db.Fruit"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "The version of the added fruit"
+                            },
+                            "Location": {
+                                "type": "string",
+                                "description": "The URL of the added fruit"
+                            }
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
                         "schema": {
-                            "$ref": "#/definitions/utils.HTTPError"
+                            "$ref": "#/definitions/utils.Problem"
                         }
                     }
                 }
             }
         },
-        "/fruits/search/{name}": {
-            "get": {
-                "description": "Gets list of fruits by name",
+        "/fruits/batch": {
+            "post": {
+                "description": "Applies a list of operations. An insert adds the fruit, an upsert updates the fruit having\nthe same name ignoring the case or adds it when there is none and a delete moves the fruit\nwith the id to the trash. In the atomic mode either all the operations are applied or none\nof them, in the best-effort mode each operation is applied on its own.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "fruit"
                 ],
-                "summary": "Gets fruits by name",
+                "summary": "Insert, upsert and delete fruits in one request",
                 "parameters": [
                     {
+                        "enum": [
+                            "atomic",
+                            "best-effort"
+                        ],
                         "type": "string",
-                        "description": "Full or partial name of the fruit",
-                        "name": "name",
-                        "in": "path",
-                        "required": true
+                        "default": "atomic",
+                        "description": "How to apply the operations",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Operations to apply",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/db.BatchOperation"
+                            }
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "All the operations succeeded",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/db.Fruit"
-                            }
+                            "$ref": "#/definitions/routes.BatchResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "207": {
+                        "description": "Some of the operations failed, see the status of each result",
+                        "schema": {
+                            "$ref": "#/definitions/routes.BatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/utils.HTTPError"
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
                         }
                     }
                 }
             }
         },
-        "/fruits/season/{season}": {
+        "/fruits/export": {
             "get": {
-                "description": "Gets a list of fruits by season",
+                "description": "Writes the fruits that are not in the trash ordered by their ids, each fruit is written as it is\nread from the database. The CSV and NDJSON exports could be imported back, the YAML has the\ndbfixture layout of the data.yaml and the SQL has an INSERT statement per fruit keeping its id.",
                 "produces": [
-                    "application/json"
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/yaml",
+                    "application/sql"
                 ],
                 "tags": [
                     "fruit"
                 ],
-                "summary": "Gets fruits by season",
+                "summary": "Export the fruits as CSV, NDJSON, YAML or SQL",
                 "parameters": [
                     {
+                        "enum": [
+                            "csv",
+                            "ndjson",
+                            "yaml",
+                            "sql"
+                        ],
                         "type": "string",
-                        "description": "Full or partial name of the season",
-                        "name": "season",
-                        "in": "path",
-                        "required": true
+                        "default": "csv",
+                        "description": "The format of the export",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "sqlite",
+                            "pgsql",
+                            "mysql"
+                        ],
+                        "type": "string",
+                        "description": "The database the SQL statements are written for, the one of the API when it is not set",
+                        "name": "dialect",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "The fruits",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/db.Fruit"
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Content-Disposition": {
+                                "type": "string",
+                                "description": "The file name of the export"
                             }
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/utils.HTTPError"
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
                         }
                     }
                 }
             }
         },
-        "/fruits/{id}": {
-            "delete": {
-                "description": "Deletes a Fruit to the Database",
+        "/fruits/import": {
+            "post": {
+                "description": "Adds the fruits of the request body, which is read as it is received so it could be larger than\nthe memory. The CSV has a header row naming its name, season and emoji columns, the NDJSON has a\nfruit object per line and the YAML has the dbfixture layout of the data.yaml. The rows having the\nname of an existing fruit are skipped unless update is set. Each row is validated, the invalid rows\nare rejected with their line numbers and the valid ones are imported. With dry_run the changes\nare reported without being made.",
+                "consumes": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/yaml"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
                     "fruit"
                 ],
-                "summary": "Delete a fruit from Database",
+                "summary": "Import fruits from CSV, NDJSON or YAML",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Fruit ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "enum": [
+                            "csv",
+                            "ndjson",
+                            "yaml"
+                        ],
+                        "type": "string",
+                        "description": "The format of the body, told by its Content-Type when it is not set",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Update the fruits having the names of the rows",
+                        "name": "update",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Only report the changes the import would make",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "description": "The fruits to import",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "All the rows were imported",
+                        "schema": {
+                            "$ref": "#/definitions/db.ImportResult"
+                        }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "207": {
+                        "description": "Some of the rows were rejected",
+                        "schema": {
+                            "$ref": "#/definitions/db.ImportResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
                         "schema": {
-                            "$ref": "#/definitions/utils.HTTPError"
+                            "$ref": "#/definitions/utils.Problem"
                         }
                     }
                 }
             }
         },
-        "/health/live/": {
+        "/fruits/search/{name}": {
             "get": {
-                "description": "Checks the API liveness, can be used with Kubernetes Probes",
+                "description": "Gets list of fruits by name",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "health"
+                    "fruit"
+                ],
+                "summary": "Gets fruits by name",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Full or partial name of the fruit",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Maximum number of fruits to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of fruits to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields to sort on, prefix a field with '-' to sort descending e.g. -created_at,name",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor from the X-Next-Cursor header of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Checks the API liveness",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/db.Fruit"
+                            }
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Links to the next, prev, first and last pages"
+                            },
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "Cursor to get the next page"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Total number of matching fruits"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
                         }
                     }
                 }
             }
         },
-        "/health/ready/": {
+        "/fruits/season/{season}": {
             "get": {
-                "description": "Checks the API readiness, can be used with Kubernetes Probes",
+                "description": "Gets a list of fruits by season",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "health"
+                    "fruit"
+                ],
+                "summary": "Gets fruits by season",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Full or partial name of the season",
+                        "name": "season",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Maximum number of fruits to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of fruits to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields to sort on, prefix a field with '-' to sort descending e.g. -created_at,name",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor from the X-Next-Cursor header of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Checks the API readiness",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/db.Fruit"
+                            }
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Links to the next, prev, first and last pages"
+                            },
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "Cursor to get the next page"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Total number of matching fruits"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "db.Fruit": {
-            "type": "object",
-            "properties": {
-                "emoji": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "name": {
-                    "type": "string"
-                },
-                "season": {
+        },
+        "/fruits/trash": {
+            "get": {
+                "description": "Gets a list of the fruits in the trash that could be restored",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fruit"
+                ],
+                "summary": "Gets the deleted fruits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter expression e.g. season eq 'Summer' and name like 'ber'",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Maximum number of fruits to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of fruits to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields to sort on, prefix a field with '-' to sort descending e.g. -created_at,name",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor from the X-Next-Cursor header of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/db.Fruit"
+                            }
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Links to the next, prev, first and last pages"
+                            },
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "Cursor to get the next page"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Total number of matching fruits"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/fruits/{id}": {
+            "get": {
+                "description": "Gets the fruit with the given id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fruit"
+                ],
+                "summary": "Gets a fruit by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Fruit ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached fruit, the fruit is only returned when it has changed",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/db.Fruit"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "The version of the fruit"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replaces an existing Fruit in the Database",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fruit"
+                ],
+                "summary": "Update a fruit in Database",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Fruit ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fruit object",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/db.Fruit"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the fruit, the fruit is only changed when it has not changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/db.Fruit"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "The version of the changed fruit"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Moves a Fruit to the trash, it could be restored until it is purged",
+                "tags": [
+                    "fruit"
+                ],
+                "summary": "Delete a fruit from Database",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Fruit ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the fruit, the fruit is only deleted when it has not changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Partially updates an existing Fruit in the Database using JSON Merge Patch(RFC 7386)",
+                "consumes": [
+                    "application/json",
+                    "application/merge-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fruit"
+                ],
+                "summary": "Patch a fruit in Database",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Fruit ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fruit attributes to patch",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/db.Fruit"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the fruit, the fruit is only changed when it has not changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/db.Fruit"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "The version of the changed fruit"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/fruits/{id}/history": {
+            "get": {
+                "description": "Lists the changes of a Fruit oldest first, along with who made them and the fruit before and after each change",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fruit"
+                ],
+                "summary": "Get the history of a fruit",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Fruit ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/db.FruitRevision"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/fruits/{id}/history/{revision}/revert": {
+            "post": {
+                "description": "Sets the name, season and emoji of a Fruit back to what they were after one of its revisions",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fruit"
+                ],
+                "summary": "Revert a fruit to a revision",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Fruit ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision ID",
+                        "name": "revision",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the fruit, the fruit is only changed when it has not changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/db.Fruit"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "The version of the changed fruit"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/fruits/{id}/restore": {
+            "post": {
+                "description": "Brings back a Fruit from the trash",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fruit"
+                ],
+                "summary": "Restore a deleted fruit",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Fruit ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/db.Fruit"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/health/live/": {
+            "get": {
+                "description": "Checks the API liveness, can be used with Kubernetes Probes",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Checks the API liveness",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/health/ready/": {
+            "get": {
+                "description": "Checks the API readiness, can be used with Kubernetes Probes",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Checks the API readiness",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/health/startup/": {
+            "get": {
+                "description": "Reports \"starting\" with 503 until the database is reachable and migrated, \"started\" afterwards, can be used with Kubernetes startup Probes",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Checks whether the API started",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "apperrors.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "name"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "name is required"
+                },
+                "param": {
+                    "type": "string",
+                    "example": ""
+                },
+                "rule": {
+                    "type": "string",
+                    "example": "required"
+                }
+            }
+        },
+        "db.BatchOp": {
+            "type": "string",
+            "enum": [
+                "insert",
+                "upsert",
+                "delete"
+            ],
+            "x-enum-varnames": [
+                "BatchInsert",
+                "BatchUpsert",
+                "BatchDelete"
+            ]
+        },
+        "db.BatchOperation": {
+            "type": "object",
+            "properties": {
+                "fruit": {
+                    "description": "Fruit is the fruit to insert or upsert",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/db.Fruit"
+                        }
+                    ]
+                },
+                "id": {
+                    "description": "ID is the id of the fruit to delete",
+                    "type": "integer"
+                },
+                "op": {
+                    "enum": [
+                        "insert",
+                        "upsert",
+                        "delete"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/db.BatchOp"
+                        }
+                    ]
+                }
+            }
+        },
+        "db.Fruit": {
+            "type": "object",
+            "required": [
+                "name",
+                "season"
+            ],
+            "properties": {
+                "deleted_at": {
+                    "description": "DeletedAt is the time the fruit was moved to the trash, the deleted\nfruits are excluded from the queries unless asked for",
+                    "type": "string"
+                },
+                "emoji": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 64
+                },
+                "season": {
                     "type": "string"
                 }
             }
         },
-        "utils.HTTPError": {
+        "db.FruitRevision": {
             "type": "object",
             "properties": {
-                "code": {
+                "actor": {
+                    "type": "string",
+                    "example": "alice"
+                },
+                "after": {
+                    "description": "After is the fruit after the change, it is empty for the deletes",
+                    "type": "object"
+                },
+                "before": {
+                    "description": "Before is the fruit before the change, it is empty for the inserts",
+                    "type": "object"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "fruit_id": {
                     "type": "integer",
-                    "example": 400
+                    "example": 1
                 },
-                "message": {
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "operation": {
+                    "enum": [
+                        "insert",
+                        "update",
+                        "delete",
+                        "restore",
+                        "revert",
+                        "purge"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/db.RevisionOp"
+                        }
+                    ],
+                    "example": "update"
+                },
+                "version": {
+                    "description": "Version is the version of the fruit after the change, or the\ndeleted version for the deletes",
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "db.ImportAction": {
+            "type": "string",
+            "enum": [
+                "insert",
+                "update",
+                "skip",
+                "unchanged"
+            ],
+            "x-enum-varnames": [
+                "ImportInsert",
+                "ImportUpdate",
+                "ImportSkip",
+                "ImportUnchanged"
+            ]
+        },
+        "db.ImportChange": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "enum": [
+                        "insert",
+                        "update",
+                        "skip"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/db.ImportAction"
+                        }
+                    ],
+                    "example": "update"
+                },
+                "after": {
+                    "$ref": "#/definitions/db.ImportValues"
+                },
+                "before": {
+                    "description": "Before is the fruit having the name of the row, it is empty for the inserts",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/db.ImportValues"
+                        }
+                    ]
+                },
+                "id": {
+                    "description": "ID is the id of the fruit, it is not set for the inserts of a dry-run",
+                    "type": "integer",
+                    "example": 1
+                },
+                "line": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "db.ImportRejection": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "validation failed, season must be one of Spring,Summer,Fall,Winter"
+                },
+                "line": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "db.ImportResult": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "description": "Changes are the first MaxImportReport inserts, updates and skips",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.ImportChange"
+                    }
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "inserted": {
+                    "type": "integer"
+                },
+                "rejected": {
+                    "type": "integer"
+                },
+                "rejections": {
+                    "description": "Rejections are the first MaxImportReport rejected rows",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.ImportRejection"
+                    }
+                },
+                "skipped": {
+                    "type": "integer"
+                },
+                "unchanged": {
+                    "type": "integer"
+                },
+                "updated": {
+                    "type": "integer"
+                }
+            }
+        },
+        "db.ImportValues": {
+            "type": "object",
+            "properties": {
+                "emoji": {
+                    "type": "string",
+                    "example": "U+1F96D"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Mango"
+                },
+                "season": {
+                    "type": "string",
+                    "example": "Spring"
+                }
+            }
+        },
+        "db.RevisionOp": {
+            "type": "string",
+            "enum": [
+                "insert",
+                "update",
+                "delete",
+                "restore",
+                "revert",
+                "purge"
+            ],
+            "x-enum-varnames": [
+                "RevisionInsert",
+                "RevisionUpdate",
+                "RevisionDelete",
+                "RevisionRestore",
+                "RevisionRevert",
+                "RevisionPurge"
+            ]
+        },
+        "routes.BatchResponse": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/routes.BatchResult"
+                    }
+                },
+                "succeeded": {
+                    "type": "integer"
+                }
+            }
+        },
+        "routes.BatchResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Error is the reason the operation failed",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/utils.Problem"
+                        }
+                    ]
+                },
+                "fruit": {
+                    "description": "Fruit is the inserted or upserted fruit",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/db.Fruit"
+                        }
+                    ]
+                },
+                "index": {
+                    "description": "Index is the position of the operation in the request",
+                    "type": "integer"
+                },
+                "op": {
+                    "description": "Op is the operation",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/db.BatchOp"
+                        }
+                    ]
+                },
+                "status": {
+                    "description": "Status is the HTTP status code the operation would have got as a single request,\n424 Failed Dependency when it was rolled back because another operation failed",
+                    "type": "integer"
+                }
+            }
+        },
+        "utils.Problem": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string",
+                    "example": "fruit with id 5 not found"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apperrors.FieldError"
+                    }
+                },
+                "existing_id": {
+                    "description": "ExistingID is the id of the existing resource a conflicting change collides with",
+                    "type": "integer",
+                    "example": 1
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/api/fruits/5"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 404
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Not Found"
+                },
+                "type": {
                     "type": "string",
-                    "example": "status bad request"
+                    "example": "https://github.com/kameshsampath/go-fruits-api/blob/main/docs/problems.md#not-found"
                 }
             }
         }
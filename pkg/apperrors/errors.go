@@ -0,0 +1,150 @@
+// Package apperrors defines the typed domain errors of the Fruits API, the
+// HTTP layer maps each of them to a problem response with a stable type.
+package apperrors
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NotFoundError is returned when the requested resource does not exist
+type NotFoundError struct {
+	Resource string
+	ID       interface{}
+}
+
+// NotFound creates the NotFoundError for the resource with the id
+func NotFound(resource string, id interface{}) *NotFoundError {
+	return &NotFoundError{
+		Resource: resource,
+		ID:       id,
+	}
+}
+
+// Error implements error
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s with id %v not found", e.Resource, e.ID)
+}
+
+// ConflictError is returned when the change conflicts with the current
+// state of the resource
+type ConflictError struct {
+	Message string
+	// ExistingID is the id of the existing resource the change conflicts with, if known
+	ExistingID interface{}
+}
+
+// Conflict creates the ConflictError with the formatted message
+func Conflict(format string, args ...interface{}) *ConflictError {
+	return &ConflictError{
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// AlreadyExists creates the ConflictError for the resource named name that
+// conflicts with the existing resource having the id
+func AlreadyExists(resource string, name string, id interface{}) *ConflictError {
+	return &ConflictError{
+		Message:    fmt.Sprintf("%s %q already exists with id %v", resource, name, id),
+		ExistingID: id,
+	}
+}
+
+// Error implements error
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+// PreconditionFailedError is returned when the resource was changed since
+// the version the client expects to change
+type PreconditionFailedError struct {
+	Resource string
+	ID       interface{}
+}
+
+// PreconditionFailed creates the PreconditionFailedError for the resource with the id
+func PreconditionFailed(resource string, id interface{}) *PreconditionFailedError {
+	return &PreconditionFailedError{
+		Resource: resource,
+		ID:       id,
+	}
+}
+
+// Error implements error
+func (e *PreconditionFailedError) Error() string {
+	return fmt.Sprintf("%s with id %v was modified, get the latest version and retry", e.Resource, e.ID)
+}
+
+// FieldError describes a field that failed the validation
+type FieldError struct {
+	Field   string `json:"field" example:"name"`
+	Rule    string `json:"rule" example:"required"`
+	Param   string `json:"param,omitempty" example:""`
+	Message string `json:"message" example:"name is required"`
+}
+
+// ValidationError is returned when the payload has invalid fields
+type ValidationError struct {
+	Errors []FieldError
+}
+
+// Validation creates the ValidationError for the fieldErrors
+func Validation(fieldErrors ...FieldError) *ValidationError {
+	return &ValidationError{
+		Errors: fieldErrors,
+	}
+}
+
+// Error implements error
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		msgs = append(msgs, fe.Message)
+	}
+	return fmt.Sprintf("validation failed, %s", strings.Join(msgs, ", "))
+}
+
+// BadRequestError is returned when the request parameters are invalid
+type BadRequestError struct {
+	Err error
+}
+
+// BadRequest wraps the err as BadRequestError
+func BadRequest(err error) *BadRequestError {
+	return &BadRequestError{
+		Err: err,
+	}
+}
+
+// Error implements error
+func (e *BadRequestError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *BadRequestError) Unwrap() error {
+	return e.Err
+}
+
+// UnavailableError is returned when a backing service like the database
+// could not be reached
+type UnavailableError struct {
+	Err error
+}
+
+// Unavailable wraps the err as UnavailableError
+func Unavailable(err error) *UnavailableError {
+	return &UnavailableError{
+		Err: err,
+	}
+}
+
+// Error implements error
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("service unavailable, %v", e.Err)
+}
+
+// Unwrap returns the wrapped error
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,358 @@
+package db
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+	"github.com/sirupsen/logrus"
+	"github.com/uptrace/bun/dialect"
+)
+
+// backupTimeFormat is the layout of the time in the names of the backups, the
+// names sort in the order the backups were taken
+const backupTimeFormat = "20060102T150405.000Z"
+
+// errNotSQLite is returned by the backups of the pgsql and mysql databases,
+// which are backed up with their own tools
+var errNotSQLite = apperrors.BadRequest(errors.New("backups are only supported by the sqlite database"))
+
+// BackupInfo describes a backup of the sqlite database
+type BackupInfo struct {
+	Name       string    `json:"name" example:"fruits-20230115T093000.000Z.db.gz"`
+	Size       int64     `json:"size" example:"4096"`
+	Compressed bool      `json:"compressed" example:"true"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Backup writes a consistent snapshot of the sqlite database to the file with
+// VACUUM INTO, gzipped when compress is true. The database is served while it
+// is backed up, the snapshot is written next to the file and renamed to it once
+// it is complete so that the file is never a partial backup.
+func (c *Config) Backup(ctx context.Context, file string, compress bool) (*BackupInfo, error) {
+	if c.DB.Dialect().Name() != dialect.SQLite {
+		return nil, errNotSQLite
+	}
+	dir := filepath.Dir(file)
+	snapshot, err := tempName(dir, ".backup-*.db")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(snapshot)
+	if _, err := c.DB.ExecContext(ctx, "VACUUM INTO ?", snapshot); err != nil {
+		return nil, fmt.Errorf("error backing up the database, %w", err)
+	}
+	done := snapshot
+	if compress {
+		gz, err := tempName(dir, ".backup-*.db.gz")
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(gz)
+		if err := copyFile(gz, snapshot, func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }, nil); err != nil {
+			return nil, err
+		}
+		done = gz
+	} else if err := syncFile(snapshot); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(done, file); err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	return &BackupInfo{
+		Name:       filepath.Base(file),
+		Size:       fi.Size(),
+		Compressed: compress,
+		CreatedAt:  fi.ModTime(),
+	}, nil
+}
+
+// RestoreResult describes a restored sqlite database
+type RestoreResult struct {
+	// Fruits is the number of fruits of the restored database, the ones in the trash too
+	Fruits int
+	// Pending is the number of migrations the restored database lacks, they are
+	// applied on the next start
+	Pending int
+	// Previous is the file the replaced database was moved to, empty when there was none
+	Previous string
+}
+
+// RestoreSQLite replaces the sqlite database dbFile with the snapshot, which is
+// gunzipped when its name ends with .gz. The snapshot is checked to be an intact
+// fruits database whose migrations are known to this version before it is
+// swapped in, the replaced database is kept next to it. The database must not be
+// served while it is restored.
+func RestoreSQLite(ctx context.Context, log *logrus.Logger, snapshot, dbFile string) (*RestoreResult, error) {
+	dir := filepath.Dir(dbFile)
+	restored, err := tempName(dir, ".restore-*.db")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(restored)
+	var decompress func(r io.Reader) (io.Reader, error)
+	if strings.HasSuffix(snapshot, ".gz") {
+		decompress = func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }
+	}
+	if err := copyFile(restored, snapshot, nil, decompress); err != nil {
+		return nil, fmt.Errorf("unable to read the snapshot, %w", err)
+	}
+	res, err := checkSnapshot(ctx, log, restored)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(dbFile); err == nil {
+		res.Previous = dbFile + ".before-restore-" + time.Now().UTC().Format(backupTimeFormat)
+		//a hot journal left next to the database would be rolled back into the restored one
+		for _, suffix := range []string{"", "-journal", "-wal", "-shm"} {
+			if err := os.Rename(dbFile+suffix, res.Previous+suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+		}
+	}
+	if err := os.Rename(restored, dbFile); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// checkSnapshot checks that the sqlite database file is intact and that it has
+// the fruits schema of this version or of an earlier one
+func checkSnapshot(ctx context.Context, log *logrus.Logger, file string) (*RestoreResult, error) {
+	c := New(
+		WithLogger(log),
+		WithDBType("sqlite"),
+		WithDBFile(file),
+		WithAutoMigrate(false),
+		WithRetry(Retry{}))
+	if err := c.Init(ctx); err != nil {
+		return nil, err
+	}
+	defer c.DB.Close()
+	var integrity string
+	if err := c.DB.NewRaw("PRAGMA integrity_check").Scan(ctx, &integrity); err != nil {
+		return nil, fmt.Errorf("the snapshot is not a sqlite database, %w", err)
+	}
+	if integrity != "ok" {
+		return nil, fmt.Errorf("the snapshot is corrupted, %s", integrity)
+	}
+
+	m, err := c.Migrator()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Init(ctx); err != nil {
+		return nil, err
+	}
+	unknown, err := m.MissingMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(unknown) > 0 {
+		names := make([]string, len(unknown))
+		for i, m := range unknown {
+			names[i] = m.Name
+		}
+		return nil, fmt.Errorf("the snapshot has the migrations %s unknown to this version, restore it with the version that took it", strings.Join(names, ","))
+	}
+	ms, err := m.MigrationsWithStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(ms.Applied()) == 0 {
+		return nil, errors.New("the snapshot is not a fruits database, it has no applied migrations")
+	}
+	fruits, err := c.DB.NewSelect().Table("fruits").Count(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("the snapshot has no fruits table, %w", err)
+	}
+	return &RestoreResult{Fruits: fruits, Pending: len(ms.Unapplied())}, nil
+}
+
+// BackupDir is a directory of backups of the sqlite database, the backups are
+// named after the time they were taken
+type BackupDir string
+
+// Backup backs up the database to a new backup of the dir
+func (d BackupDir) Backup(ctx context.Context, c *Config, compress bool) (*BackupInfo, error) {
+	name := "fruits-" + time.Now().UTC().Format(backupTimeFormat) + ".db"
+	if compress {
+		name += ".gz"
+	}
+	return c.Backup(ctx, filepath.Join(string(d), name), compress)
+}
+
+// List lists the backups of the dir, the newest first
+func (d BackupDir) List() ([]*BackupInfo, error) {
+	entries, err := os.ReadDir(string(d))
+	if err != nil {
+		return nil, err
+	}
+	backups := []*BackupInfo{}
+	for _, e := range entries {
+		if e.IsDir() || !isBackupName(e.Name()) {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, &BackupInfo{
+			Name:       e.Name(),
+			Size:       fi.Size(),
+			Compressed: strings.HasSuffix(e.Name(), ".gz"),
+			CreatedAt:  fi.ModTime(),
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Name > backups[j].Name
+	})
+	return backups, nil
+}
+
+// File returns the file of the backup named name
+func (d BackupDir) File(name string) (string, error) {
+	//the name is checked so that it does not reach out of the dir
+	if !isBackupName(name) {
+		return "", apperrors.NotFound("backup", name)
+	}
+	file := filepath.Join(string(d), name)
+	if _, err := os.Stat(file); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", apperrors.NotFound("backup", name)
+		}
+		return "", err
+	}
+	return file, nil
+}
+
+// Prune removes the backups of the dir but the newest keep ones, it returns
+// the names of the removed backups
+func (d BackupDir) Prune(keep int) ([]string, error) {
+	backups, err := d.List()
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for i := keep; i < len(backups); i++ {
+		if err := os.Remove(filepath.Join(string(d), backups[i].Name)); err != nil {
+			return removed, err
+		}
+		removed = append(removed, backups[i].Name)
+	}
+	return removed, nil
+}
+
+// isBackupName tells whether the file name is the name of a backup
+func isBackupName(name string) bool {
+	ts := strings.TrimSuffix(name, ".gz")
+	if !strings.HasPrefix(ts, "fruits-") || !strings.HasSuffix(ts, ".db") {
+		return false
+	}
+	_, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(ts, "fruits-"), ".db"))
+	return err == nil
+}
+
+// ScheduleBackups backs up the database to the dir every interval until the ctx
+// is done, after each backup the backups but the newest keep ones are removed
+// unless keep is zero
+func ScheduleBackups(ctx context.Context, log *logrus.Logger, c *Config, dir BackupDir, interval time.Duration, keep int, compress bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		info, err := dir.Backup(ctx, c, compress)
+		if err != nil {
+			log.Errorf("Error backing up the database, %v", err)
+			continue
+		}
+		log.Infof("Backed up the database to %s", info.Name)
+		if keep == 0 {
+			continue
+		}
+		removed, err := dir.Prune(keep)
+		if err != nil {
+			log.Errorf("Error removing the old backups, %v", err)
+		} else if len(removed) > 0 {
+			log.Infof("Removed the backups %s, keeping the newest %d", strings.Join(removed, ","), keep)
+		}
+	}
+}
+
+// tempName returns the name of a new temporary file of the dir, the file does
+// not exist as VACUUM INTO requires
+func tempName(dir, pattern string) (string, error) {
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	return f.Name(), os.Remove(f.Name())
+}
+
+// copyFile copies the file src to dst through the optional compress writer or
+// decompress reader, dst is synced to the disk
+func copyFile(dst, src string, compress func(w io.Writer) io.WriteCloser, decompress func(r io.Reader) (io.Reader, error)) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	var r io.Reader = in
+	if decompress != nil {
+		if r, err = decompress(in); err != nil {
+			return err
+		}
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	var w io.WriteCloser = out
+	if compress != nil {
+		w = compress(out)
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		return err
+	}
+	if compress != nil {
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+	if err := out.Sync(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// syncFile flushes the file to the disk, VACUUM INTO does not
+func syncFile(name string) error {
+	f, err := os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,178 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/uptrace/bun/migrate"
+)
+
+func TestBackupRestore(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	log := utils.LogSetup(os.Stdout, utils.LookupEnvOrString("TEST_LOG_LEVEL", "info"))
+	dbc := newSeedDB(t, ctx)
+	repo := NewBunFruitRepository(dbc)
+	if err := repo.Create(ctx, &Fruit{Name: "Mango", Season: "Spring"}); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	for name, compress := range map[string]bool{"fruits.db": false, "fruits.db.gz": true} {
+		t.Run(name, func(t *testing.T) {
+			file := path.Join(dir, name)
+			info, err := dbc.Backup(ctx, file, compress)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, name, info.Name)
+			assert.Equal(t, compress, info.Compressed)
+			assert.Positive(t, info.Size)
+
+			//the restore replaces the database and keeps the replaced one
+			dbFile := path.Join(t.TempDir(), "restored.db")
+			if err := os.WriteFile(dbFile, []byte("replaced"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			res, err := RestoreSQLite(ctx, log, file, dbFile)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, 1, res.Fruits)
+			assert.Zero(t, res.Pending)
+			if b, err := os.ReadFile(res.Previous); assert.NoError(t, err) {
+				assert.Equal(t, "replaced", string(b))
+			}
+
+			restored := New(WithLogger(log), WithDBType("sqlite"), WithDBFile(dbFile))
+			if err := restored.Init(ctx); err != nil {
+				t.Fatal(err)
+			}
+			defer restored.DB.Close()
+			f, err := NewBunFruitRepository(restored).Get(ctx, 1)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "Mango", f.Name)
+			}
+		})
+	}
+}
+
+func TestRestoreErrors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	log := utils.LogSetup(os.Stdout, utils.LookupEnvOrString("TEST_LOG_LEVEL", "info"))
+	testCases := map[string]struct {
+		snapshot func(t *testing.T, file string)
+		wantErr  string
+	}{
+		"notSQLite": {
+			snapshot: func(t *testing.T, file string) {
+				if err := os.WriteFile(file, []byte("name,season\nMango,Spring\n"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "the snapshot is not a sqlite database",
+		},
+		"notFruits": {
+			snapshot: func(t *testing.T, file string) {
+				if err := os.WriteFile(file, nil, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "the snapshot is not a fruits database, it has no applied migrations",
+		},
+		"newerVersion": {
+			snapshot: func(t *testing.T, file string) {
+				dbc := New(WithLogger(log), WithDBType("sqlite"), WithDBFile(file))
+				if err := dbc.Init(ctx); err != nil {
+					t.Fatal(err)
+				}
+				defer dbc.DB.Close()
+				if _, err := dbc.DB.NewInsert().
+					Model(&migrate.Migration{Name: "29991231000000", Comment: "from_the_future", GroupID: 2, MigratedAt: time.Now()}).
+					ModelTableExpr("bun_migrations").
+					Exec(ctx); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "the snapshot has the migrations 29991231000000 unknown to this version",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			snapshot, dbFile := path.Join(dir, "snapshot.db"), path.Join(dir, "fruits.db")
+			tc.snapshot(t, snapshot)
+			if err := os.WriteFile(dbFile, []byte("kept"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := RestoreSQLite(ctx, log, snapshot, dbFile)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.wantErr)
+			}
+			if b, err := os.ReadFile(dbFile); assert.NoError(t, err) {
+				assert.Equal(t, "kept", string(b), "Expecting the database to be kept")
+			}
+			entries, err := os.ReadDir(dir)
+			if assert.NoError(t, err) {
+				assert.Len(t, entries, 2, "Expecting the restored snapshot to be removed")
+			}
+		})
+	}
+}
+
+func TestBackupDir(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dbc := newSeedDB(t, ctx)
+	dir := BackupDir(t.TempDir())
+	if err := os.WriteFile(path.Join(string(dir), "notes.txt"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, compress := range []bool{false, true, true} {
+		info, err := dir.Backup(ctx, dbc, compress)
+		if !assert.NoError(t, err) {
+			return
+		}
+		names = append([]string{info.Name}, names...)
+		//the names have the milliseconds of the time of the backup
+		time.Sleep(2 * time.Millisecond)
+	}
+	backups, err := dir.List()
+	if assert.NoError(t, err) && assert.Len(t, backups, 3) {
+		assert.Equal(t, names, []string{backups[0].Name, backups[1].Name, backups[2].Name}, "Expecting the newest backup first")
+		assert.True(t, backups[0].Compressed)
+		assert.False(t, backups[2].Compressed)
+	}
+
+	file, err := dir.File(names[0])
+	if assert.NoError(t, err) {
+		assert.Equal(t, path.Join(string(dir), names[0]), file)
+	}
+	for _, name := range []string{"notes.txt", "../fruits.db", "fruits-20230115T093000.000Z.db"} {
+		_, err := dir.File(name)
+		var notFound *apperrors.NotFoundError
+		assert.True(t, errors.As(err, &notFound), "Expecting %s not to be found but got %v", name, err)
+	}
+
+	removed, err := dir.Prune(2)
+	if assert.NoError(t, err) {
+		assert.Equal(t, names[2:], removed)
+	}
+	backups, err = dir.List()
+	if assert.NoError(t, err) {
+		assert.Len(t, backups, 2)
+	}
+	_, err = os.Stat(path.Join(string(dir), "notes.txt"))
+	assert.NoError(t, err, "Expecting the other files to be kept")
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+)
+
+// BatchOp is the kind of a BatchOperation
+type BatchOp string
+
+const (
+	// BatchInsert adds the fruit of the operation
+	BatchInsert BatchOp = "insert"
+	// BatchUpsert updates the fruit having the same name ignoring the case,
+	// or adds the fruit when there is none
+	BatchUpsert BatchOp = "upsert"
+	// BatchDelete moves the fruit with the operation ID to the trash
+	BatchDelete BatchOp = "delete"
+)
+
+// BatchOps are the known kinds of batch operations
+var BatchOps = []BatchOp{BatchInsert, BatchUpsert, BatchDelete}
+
+// ErrBatchRolledBack is the error of the operations that were not applied
+// because another operation of the all-or-nothing batch failed
+var ErrBatchRolledBack = errors.New("not applied, the batch was rolled back")
+
+// errBatchFailed aborts the transaction of an all-or-nothing batch
+var errBatchFailed = errors.New("batch operation failed")
+
+// BatchOperation is one change of a FruitRepository Batch
+type BatchOperation struct {
+	Op BatchOp `json:"op" enums:"insert,upsert,delete"`
+	// Fruit is the fruit to insert or upsert
+	Fruit *Fruit `json:"fruit,omitempty"`
+	// ID is the id of the fruit to delete
+	ID int `json:"id,omitempty"`
+}
+
+// BatchResult is the outcome of a BatchOperation
+type BatchResult struct {
+	// Fruit is the inserted or upserted fruit
+	Fruit *Fruit
+	// Created is true when the operation added the fruit
+	Created bool
+	// Err is the reason the operation failed
+	Err error
+}
+
+// rollback marks the results of an all-or-nothing batch as not applied,
+// but the one of the operation that failed
+func rollback(results []BatchResult) {
+	for i := range results {
+		if results[i].Err == nil {
+			results[i] = BatchResult{Err: ErrBatchRolledBack}
+		}
+	}
+}
+
+func unknownBatchOp(op BatchOp) error {
+	return apperrors.BadRequest(fmt.Errorf("unknown batch operation %q, allowed operations are %v", op, BatchOps))
+}
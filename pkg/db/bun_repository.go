@@ -0,0 +1,600 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+	"github.com/kameshsampath/go-fruits-api/pkg/query"
+	"github.com/uptrace/bun"
+)
+
+// BunFruitRepository is the FruitRepository backed by the bun database of the Config
+type BunFruitRepository struct {
+	db *bun.DB
+}
+
+var _ FruitRepository = (*BunFruitRepository)(nil)
+
+// NewBunFruitRepository creates the FruitRepository that stores the fruits
+// in the initialized database of the Config
+func NewBunFruitRepository(c *Config) *BunFruitRepository {
+	return &BunFruitRepository{
+		db: c.DB,
+	}
+}
+
+// Create implements FruitRepository
+func (r *BunFruitRepository) Create(ctx context.Context, f *Fruit) error {
+	return r.db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		return insertFruit(ctx, tx, f)
+	})
+}
+
+// Get implements FruitRepository
+func (r *BunFruitRepository) Get(ctx context.Context, id int) (*Fruit, error) {
+	if id == 0 {
+		return nil, apperrors.NotFound("fruit", id)
+	}
+	f := &Fruit{
+		ID: id,
+	}
+	if err := r.db.NewSelect().
+		Model(f).
+		WherePK().
+		Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NotFound("fruit", id)
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// List implements FruitRepository
+func (r *BunFruitRepository) List(ctx context.Context, opts ListOptions) (Fruits, int, error) {
+	return r.list(ctx, opts, false)
+}
+
+// ListDeleted implements FruitRepository
+func (r *BunFruitRepository) ListDeleted(ctx context.Context, opts ListOptions) (Fruits, int, error) {
+	return r.list(ctx, opts, true)
+}
+
+// list lists the fruits that are not deleted or the ones in the trash when deleted is true
+func (r *BunFruitRepository) list(ctx context.Context, opts ListOptions, deleted bool) (Fruits, int, error) {
+	scope := func(q *bun.SelectQuery) *bun.SelectQuery {
+		if deleted {
+			q = q.WhereDeleted()
+		}
+		return query.Where(q, opts.Filter)
+	}
+	total, err := r.db.NewSelect().
+		Model((*Fruit)(nil)).
+		Apply(scope).
+		Count(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	fruits := Fruits{}
+	q := r.db.NewSelect().
+		Model(&fruits).
+		Apply(scope)
+	if opts.After != nil {
+		after, err := opts.Sort.After(opts.After)
+		if err != nil {
+			return nil, 0, err
+		}
+		q = query.Where(q, after)
+	}
+	q = opts.Sort.Order(q)
+	if opts.Limit > 0 {
+		q = q.Limit(opts.Limit).Offset(opts.Offset)
+	}
+	if err := q.Scan(ctx); err != nil {
+		return nil, 0, err
+	}
+	return fruits, total, nil
+}
+
+// Search implements FruitRepository
+func (r *BunFruitRepository) Search(ctx context.Context, criteria Criteria, opts ListOptions) (Fruits, int, error) {
+	opts.Filter = criteria.Filter(opts.Filter)
+	return r.List(ctx, opts)
+}
+
+// Update implements FruitRepository
+func (r *BunFruitRepository) Update(ctx context.Context, f *Fruit) error {
+	return r.db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		return updateFruit(ctx, tx, f, RevisionUpdate)
+	})
+}
+
+// Delete implements FruitRepository
+func (r *BunFruitRepository) Delete(ctx context.Context, id int, version int) error {
+	return r.db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		return deleteFruit(ctx, tx, id, version)
+	})
+}
+
+func insertFruit(ctx context.Context, db bun.IDB, f *Fruit) error {
+	if err := checkNameAvailable(ctx, db, f.Name, f.ID); err != nil {
+		return err
+	}
+	if _, err := db.NewInsert().
+		Model(f).
+		Exec(ctx); err != nil {
+		return fruitConflict(err, f)
+	}
+	return recordRevision(ctx, db, RevisionInsert, nil, f)
+}
+
+// checkNameAvailable returns apperrors.ConflictError referencing the fruit other
+// than the one with the id that already has the name ignoring the case. The
+// unique index still catches the concurrent changes, but the failed statement
+// would abort the PostgreSQL transaction before the existing fruit is known.
+func checkNameAvailable(ctx context.Context, db bun.IDB, name string, id int) error {
+	existing := &Fruit{}
+	q := db.NewSelect().
+		Model(existing).
+		Column("id", "name").
+		Where("UPPER(?) = ?", bun.Ident("name"), strings.ToUpper(name)).
+		Limit(1)
+	if id != 0 {
+		q = q.Where("? <> ?", bun.Ident("id"), id)
+	}
+	err := q.Scan(ctx)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil
+	case err != nil:
+		return err
+	}
+	return apperrors.AlreadyExists("fruit", existing.Name, existing.ID)
+}
+
+// updateFruit saves the fruit f as defined by FruitRepository Update and records
+// the op revision, the modification time is set by the Fruit BeforeAppendModel hook
+func updateFruit(ctx context.Context, db bun.IDB, f *Fruit, op RevisionOp) error {
+	before := &Fruit{ID: f.ID}
+	if err := db.NewSelect().
+		Model(before).
+		WherePK().
+		Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperrors.NotFound("fruit", f.ID)
+		}
+		return err
+	}
+	expected := f.Version
+	version := expected
+	if version == 0 {
+		version = before.Version
+	}
+	if err := checkNameAvailable(ctx, db, f.Name, f.ID); err != nil {
+		return err
+	}
+	f.Version = version + 1
+	res, err := db.NewUpdate().
+		Model(f).
+		Column("name", "season", "emoji", "modified_at", "version").
+		WherePK().
+		Where("? = ?", bun.Ident("version"), version).
+		Exec(ctx)
+	if err == nil {
+		var n int64
+		if n, err = res.RowsAffected(); err == nil && n == 0 {
+			err = notUpdated(ctx, db, f.ID)
+		}
+	}
+	if err != nil {
+		f.Version = expected
+		return fruitConflict(err, f)
+	}
+	return recordRevision(ctx, db, op, before, f)
+}
+
+// deleteFruit moves the fruit with the id to the trash as defined by FruitRepository Delete
+func deleteFruit(ctx context.Context, db bun.IDB, id int, version int) error {
+	before := &Fruit{ID: id}
+	err := db.NewSelect().
+		Model(before).
+		WherePK().
+		Scan(ctx)
+	switch {
+	case errors.Is(err, sql.ErrNoRows) && version == 0:
+		return nil
+	case errors.Is(err, sql.ErrNoRows):
+		return apperrors.NotFound("fruit", id)
+	case err != nil:
+		return err
+	}
+	q := db.NewDelete().
+		Model(&Fruit{ID: id}).
+		WherePK()
+	if version != 0 {
+		q = q.Where("? = ?", bun.Ident("version"), version)
+	}
+	res, err := q.Exec(ctx)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	switch {
+	case err != nil:
+		return err
+	case n > 0:
+		return recordRevision(ctx, db, RevisionDelete, before, nil)
+	case version == 0:
+		//deleted in the meantime
+		return nil
+	}
+	return notUpdated(ctx, db, id)
+}
+
+// recordRevision saves the revision of the op changing the fruit before to after
+func recordRevision(ctx context.Context, db bun.IDB, op RevisionOp, before *Fruit, after *Fruit) error {
+	rev, err := newRevision(ctx, op, before, after)
+	if err != nil {
+		return err
+	}
+	_, err = db.NewInsert().
+		Model(rev).
+		Exec(ctx)
+	return err
+}
+
+// notUpdated gives the reason the fruit with the id was not updated by a
+// versioned query, it either does not exist or has another version
+func notUpdated(ctx context.Context, db bun.IDB, id int) error {
+	exists, err := db.NewSelect().
+		Model((*Fruit)(nil)).
+		Where("? = ?", bun.Ident("id"), id).
+		Exists(ctx)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return apperrors.NotFound("fruit", id)
+	}
+	return apperrors.PreconditionFailed("fruit", id)
+}
+
+// DeleteAll implements FruitRepository
+func (r *BunFruitRepository) DeleteAll(ctx context.Context) error {
+	return r.db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		var fruits Fruits
+		if err := tx.NewSelect().
+			Model(&fruits).
+			Scan(ctx); err != nil {
+			return err
+		}
+		if _, err := tx.NewDelete().
+			Model(&Fruits{}).
+			Where("1 = 1").
+			Exec(ctx); err != nil {
+			return err
+		}
+		for _, f := range fruits {
+			if err := recordRevision(ctx, tx, RevisionDelete, f, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Restore implements FruitRepository
+func (r *BunFruitRepository) Restore(ctx context.Context, id int) (*Fruit, error) {
+	f := &Fruit{
+		ID: id,
+	}
+	err := r.db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		if err := tx.NewSelect().
+			Model(f).
+			WherePK().
+			WhereDeleted().
+			Scan(ctx); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return apperrors.NotFound("deleted fruit", id)
+			}
+			return err
+		}
+		//another fruit might have taken the name while this one was in the trash
+		if err := checkNameAvailable(ctx, tx, f.Name, f.ID); err != nil {
+			return err
+		}
+		res, err := tx.NewUpdate().
+			Model(f).
+			Set("? = NULL", bun.Ident("deleted_at")).
+			Set("? = ? + 1", bun.Ident("version"), bun.Ident("version")).
+			WherePK().
+			WhereDeleted().
+			Exec(ctx)
+		if err != nil {
+			return fruitConflict(err, f)
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return apperrors.NotFound("deleted fruit", id)
+		}
+		before := *f
+		*f = Fruit{ID: id}
+		if err := tx.NewSelect().
+			Model(f).
+			WherePK().
+			Scan(ctx); err != nil {
+			return err
+		}
+		return recordRevision(ctx, tx, RevisionRestore, &before, f)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Purge implements FruitRepository
+func (r *BunFruitRepository) Purge(ctx context.Context, before time.Time) (int, error) {
+	var fruits Fruits
+	err := r.db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		if err := tx.NewSelect().
+			Model(&fruits).
+			WhereDeleted().
+			Where("? < ?", bun.Ident("deleted_at"), before).
+			Scan(ctx); err != nil || len(fruits) == 0 {
+			return err
+		}
+		ids := make([]int, len(fruits))
+		for i, f := range fruits {
+			ids[i] = f.ID
+			if err := recordRevision(ctx, tx, RevisionPurge, f, nil); err != nil {
+				return err
+			}
+		}
+		_, err := tx.NewDelete().
+			Model((*Fruit)(nil)).
+			WhereDeleted().
+			Where("? IN (?)", bun.Ident("id"), bun.In(ids)).
+			ForceDelete().
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(fruits), nil
+}
+
+// Batch implements FruitRepository, the operations run in a single transaction
+// with a savepoint per operation when the batch is not atomic
+func (r *BunFruitRepository) Batch(ctx context.Context, ops []BatchOperation, atomic bool) ([]BatchResult, error) {
+	results := make([]BatchResult, len(ops))
+	err := r.db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		for i, op := range ops {
+			if atomic {
+				if results[i].Err = applyBatchOp(ctx, tx, op, &results[i]); results[i].Err != nil {
+					return errBatchFailed
+				}
+				continue
+			}
+			results[i].Err = tx.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, sp bun.Tx) error {
+				return applyBatchOp(ctx, sp, op, &results[i])
+			})
+			if results[i].Err != nil {
+				results[i].Fruit = nil
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errBatchFailed) {
+		rollback(results)
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// applyBatchOp applies the batch operation op and sets its result res
+func applyBatchOp(ctx context.Context, db bun.IDB, op BatchOperation, res *BatchResult) error {
+	switch op.Op {
+	case BatchInsert:
+		if err := insertFruit(ctx, db, op.Fruit); err != nil {
+			return err
+		}
+		res.Fruit, res.Created = op.Fruit, true
+	case BatchUpsert:
+		existing := &Fruit{}
+		err := db.NewSelect().
+			Model(existing).
+			Where("UPPER(?) = ?", bun.Ident("name"), strings.ToUpper(op.Fruit.Name)).
+			OrderExpr("? ASC", bun.Ident("id")).
+			Limit(1).
+			Scan(ctx)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			op.Fruit.ID = 0
+			if err := insertFruit(ctx, db, op.Fruit); err != nil {
+				return err
+			}
+			res.Fruit, res.Created = op.Fruit, true
+		case err != nil:
+			return err
+		default:
+			op.Fruit.ID = existing.ID
+			op.Fruit.CreatedAt = existing.CreatedAt
+			op.Fruit.Version = existing.Version
+			if err := updateFruit(ctx, db, op.Fruit, RevisionUpdate); err != nil {
+				return err
+			}
+			res.Fruit = op.Fruit
+		}
+	case BatchDelete:
+		return deleteFruit(ctx, db, op.ID, 0)
+	default:
+		return unknownBatchOp(op.Op)
+	}
+	return nil
+}
+
+// Import implements FruitRepository, the rows are applied in a single transaction
+// with a savepoint per row so that a failed row is rejected on its own
+func (r *BunFruitRepository) Import(ctx context.Context, rd ImportReader, opts ImportOptions) (*ImportResult, error) {
+	res := newImportResult(opts.DryRun)
+	err := r.db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		err := importRows(ctx, rd, opts, res, func(row *ImportRow) error {
+			var existing *Fruit
+			f := &Fruit{}
+			err := tx.NewSelect().
+				Model(f).
+				Where("UPPER(?) = ?", bun.Ident("name"), strings.ToUpper(row.Fruit.Name)).
+				OrderExpr("? ASC", bun.Ident("id")).
+				Limit(1).
+				Scan(ctx)
+			switch {
+			case err == nil:
+				existing = f
+			case !errors.Is(err, sql.ErrNoRows):
+				return err
+			}
+			action := importAction(existing, row.Fruit, opts.Update)
+			err = tx.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, sp bun.Tx) error {
+				switch action {
+				case ImportInsert:
+					row.Fruit.ID = 0
+					return insertFruit(ctx, sp, row.Fruit)
+				case ImportUpdate:
+					row.Fruit.ID = existing.ID
+					row.Fruit.CreatedAt = existing.CreatedAt
+					row.Fruit.Version = existing.Version
+					return updateFruit(ctx, sp, row.Fruit, RevisionUpdate)
+				}
+				return nil
+			})
+			if err != nil {
+				res.reject(row.Line, err)
+				return nil
+			}
+			res.record(row, action, existing)
+			return nil
+		})
+		if err == nil && opts.DryRun {
+			return errImportDryRun
+		}
+		return err
+	})
+	if err != nil && !errors.Is(err, errImportDryRun) {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Export implements FruitRepository, the rows are scanned as they are read from
+// the database
+func (r *BunFruitRepository) Export(ctx context.Context, fn func(f *Fruit) error) error {
+	rows, err := r.db.NewSelect().
+		Model((*Fruit)(nil)).
+		OrderExpr("? ASC", bun.Ident("id")).
+		Rows(ctx)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		f := &Fruit{}
+		if err := r.db.ScanRow(ctx, rows, f); err != nil {
+			return err
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// CountBySeason implements FruitRepository with a single query grouping the fruits
+// by their season
+func (r *BunFruitRepository) CountBySeason(ctx context.Context) (map[string]int, error) {
+	var rows []struct {
+		Season string
+		Count  int
+	}
+	if err := r.db.NewSelect().
+		Model((*Fruit)(nil)).
+		Column("season").
+		ColumnExpr("COUNT(*) AS ?", bun.Ident("count")).
+		Group("season").
+		Scan(ctx, &rows); err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, row := range rows {
+		//the fruits saved before the seasons were spelled the same way are counted together
+		counts[CanonicalSeason(row.Season)] += row.Count
+	}
+	return counts, nil
+}
+
+// History implements FruitRepository
+func (r *BunFruitRepository) History(ctx context.Context, id int) (FruitRevisions, error) {
+	revisions := FruitRevisions{}
+	if err := r.db.NewSelect().
+		Model(&revisions).
+		Where("? = ?", bun.Ident("fruit_id"), id).
+		OrderExpr("? ASC", bun.Ident("id")).
+		Scan(ctx); err != nil {
+		return nil, err
+	}
+	if len(revisions) > 0 {
+		return revisions, nil
+	}
+	//the fruits added before the revisions were recorded have no history
+	exists, err := r.db.NewSelect().
+		Model((*Fruit)(nil)).
+		WhereAllWithDeleted().
+		Where("? = ?", bun.Ident("id"), id).
+		Exists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, apperrors.NotFound("fruit", id)
+	}
+	return revisions, nil
+}
+
+// Revert implements FruitRepository
+func (r *BunFruitRepository) Revert(ctx context.Context, id int, revision int, version int) (*Fruit, error) {
+	var f *Fruit
+	err := r.db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		rev := &FruitRevision{}
+		if err := tx.NewSelect().
+			Model(rev).
+			Where("? = ?", bun.Ident("id"), revision).
+			Where("? = ?", bun.Ident("fruit_id"), id).
+			Scan(ctx); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return apperrors.NotFound("revision", revision)
+			}
+			return err
+		}
+		var err error
+		if f, err = revertTo(rev, version); err != nil {
+			return err
+		}
+		return updateFruit(ctx, tx, f, RevisionRevert)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Ping implements FruitRepository
+func (r *BunFruitRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
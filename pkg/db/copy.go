@@ -0,0 +1,359 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect"
+)
+
+// DefaultCopyBatchSize is the number of rows CopyTo inserts in a transaction
+// when the CopyOptions don't tell it
+const DefaultCopyBatchSize = 500
+
+// CopyOptions configures how CopyTo copies the database
+type CopyOptions struct {
+	// BatchSize is the number of rows inserted in each transaction
+	BatchSize int
+	// Progress, when set, is called after each batch with the number of rows
+	// of the table copied so far
+	Progress func(table string, copied int)
+}
+
+// CopiedTable is a table copied by CopyTo
+type CopiedTable struct {
+	Name string
+	Rows int
+	// Checksum is the XOR of the SHA-256 of the rows, it is the same for the
+	// source and the target tables whatever the order of their rows
+	Checksum string
+}
+
+// CopyTo copies the fruits, the ones in the trash too, their revisions and the
+// applied seed datasets to the database to, keeping their ids. The schema of to
+// is migrated and its tables have to be empty, the rows are inserted in batches
+// each in its own transaction. Afterwards the id sequences of to are moved past
+// the copied ids and the copied tables are verified by counting their rows and
+// comparing their checksums. The times are compared to the second, mysql does
+// not keep their fractions. The rows changed while they are copied are not
+// copied again, the applications writing to the database should be stopped.
+func (c *Config) CopyTo(ctx context.Context, to *Config, opts CopyOptions) ([]CopiedTable, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultCopyBatchSize
+	}
+	ms, err := c.MigrationStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if pending := ms.Unapplied(); len(pending) > 0 {
+		return nil, fmt.Errorf("the source database has %d pending migrations, run 'migrate up' on it first", len(pending))
+	}
+	if _, err := to.Migrate(ctx); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, t := range copyTables {
+		n, err := to.DB.NewSelect().Table(t.name).Count(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			return nil, fmt.Errorf("the %s table of the target database has %d rows, copy to an empty database", t.name, n)
+		}
+	}
+
+	copied := make([]CopiedTable, 0, len(copyTables))
+	for _, t := range copyTables {
+		ct := CopiedTable{Name: t.name}
+		var sum checksum
+		err := t.scan(ctx, c.DB, opts.BatchSize, func(b copyBatch) error {
+			if err := to.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+				return b.insert(ctx, tx)
+			}); err != nil {
+				return err
+			}
+			if err := b.hash(&sum); err != nil {
+				return err
+			}
+			ct.Rows += b.len()
+			if opts.Progress != nil {
+				opts.Progress(t.name, ct.Rows)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error copying the %s table after %d rows, the copied rows are kept in the target database, %w", t.name, ct.Rows, err)
+		}
+		ct.Checksum = sum.String()
+		copied = append(copied, ct)
+	}
+
+	if err := fixSequences(ctx, to.DB); err != nil {
+		return nil, fmt.Errorf("error moving the id sequences of the target database, %w", err)
+	}
+	for i, t := range copyTables {
+		if err := t.verify(ctx, to.DB, copied[i], opts.BatchSize); err != nil {
+			return nil, err
+		}
+	}
+	return copied, nil
+}
+
+// verify checks that the table of the database has the rows and the checksum
+// of the copied table ct
+func (t copyTable) verify(ctx context.Context, db *bun.DB, ct CopiedTable, batchSize int) error {
+	n, err := db.NewSelect().Table(t.name).Count(ctx)
+	if err != nil {
+		return err
+	}
+	if n != ct.Rows {
+		return fmt.Errorf("the %s table of the target database has %d rows, %d were copied", t.name, n, ct.Rows)
+	}
+	var sum checksum
+	if err := t.scan(ctx, db, batchSize, func(b copyBatch) error {
+		return b.hash(&sum)
+	}); err != nil {
+		return err
+	}
+	if sum.String() != ct.Checksum {
+		return fmt.Errorf("the checksum %s of the %s table of the target database is not the one copied %s", sum.String(), t.name, ct.Checksum)
+	}
+	return nil
+}
+
+// fixSequences moves the id sequences past the copied ids, so that the next
+// inserted rows don't reuse them. The sqlite rowids follow the largest id.
+func fixSequences(ctx context.Context, db *bun.DB) error {
+	for _, table := range []string{"fruits", "fruit_revisions"} {
+		var err error
+		switch db.Dialect().Name() {
+		case dialect.PG:
+			_, err = db.ExecContext(ctx, "SELECT setval(pg_get_serial_sequence(?, 'id'), COALESCE((SELECT MAX(?) FROM ?), 0) + 1, false)",
+				table, bun.Ident("id"), bun.Ident(table))
+		case dialect.MySQL:
+			var next int
+			if err = db.NewSelect().
+				Table(table).
+				ColumnExpr("COALESCE(MAX(?), 0) + 1", bun.Ident("id")).
+				Scan(ctx, &next); err != nil {
+				return err
+			}
+			_, err = db.ExecContext(ctx, "ALTER TABLE ? AUTO_INCREMENT = ?", bun.Ident(table), next)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyTable is a table copied by CopyTo
+type copyTable struct {
+	name string
+	// newBatch returns an empty batch of the rows of the table
+	newBatch func() copyBatch
+}
+
+// copyTables are the tables copied by CopyTo, the bun_migrations are written by
+// migrating the target database
+var copyTables = []copyTable{
+	{name: "fruits", newBatch: func() copyBatch { return &fruitBatch{} }},
+	{name: "fruit_revisions", newBatch: func() copyBatch { return &revisionBatch{} }},
+	{name: "seeds", newBatch: func() copyBatch { return &seedBatch{} }},
+}
+
+// scan reads the rows of the table a batch at a time and calls fn with each batch
+func (t copyTable) scan(ctx context.Context, db bun.IDB, batchSize int, fn func(b copyBatch) error) error {
+	var after interface{}
+	for {
+		b := t.newBatch()
+		if err := b.read(ctx, db, after, batchSize); err != nil {
+			return err
+		}
+		if b.len() == 0 {
+			return nil
+		}
+		if err := fn(b); err != nil {
+			return err
+		}
+		if b.len() < batchSize {
+			return nil
+		}
+		after = b.last()
+	}
+}
+
+// copyBatch is a batch of the rows of a copied table
+type copyBatch interface {
+	// read reads up to limit rows ordered by their primary key, the ones whose
+	// key is after the key after unless it is nil
+	read(ctx context.Context, db bun.IDB, after interface{}, limit int) error
+	len() int
+	// last returns the primary key of the last row
+	last() interface{}
+	insert(ctx context.Context, db bun.IDB) error
+	// hash adds the rows to the checksum
+	hash(sum *checksum) error
+}
+
+// readBatch selects the rows of the model ordered by the primary key pk
+func readBatch(q *bun.SelectQuery, pk string, after interface{}, limit int) *bun.SelectQuery {
+	if after != nil {
+		q = q.Where("? > ?", bun.Ident(pk), after)
+	}
+	return q.OrderExpr("? ASC", bun.Ident(pk)).Limit(limit)
+}
+
+// copiedFruit is a Fruit without its hooks, the copied fruits keep their times
+type copiedFruit Fruit
+
+type fruitBatch []*copiedFruit
+
+func (b *fruitBatch) read(ctx context.Context, db bun.IDB, after interface{}, limit int) error {
+	return readBatch(db.NewSelect().Model(b).WhereAllWithDeleted(), "id", after, limit).Scan(ctx)
+}
+
+func (b *fruitBatch) len() int {
+	return len(*b)
+}
+
+func (b *fruitBatch) last() interface{} {
+	return (*b)[len(*b)-1].ID
+}
+
+func (b *fruitBatch) insert(ctx context.Context, db bun.IDB) error {
+	_, err := db.NewInsert().Model(b).Exec(ctx)
+	return err
+}
+
+func (b *fruitBatch) hash(sum *checksum) error {
+	for _, f := range *b {
+		sum.add(strconv.Itoa(f.ID), f.Name, f.Season, f.Emoji, hashTime(f.CreatedAt), hashTime(f.ModifiedAt),
+			strconv.Itoa(f.Version), hashTimePtr(f.DeletedAt))
+	}
+	return nil
+}
+
+type revisionBatch FruitRevisions
+
+func (b *revisionBatch) read(ctx context.Context, db bun.IDB, after interface{}, limit int) error {
+	return readBatch(db.NewSelect().Model(b), "id", after, limit).Scan(ctx)
+}
+
+func (b *revisionBatch) len() int {
+	return len(*b)
+}
+
+func (b *revisionBatch) last() interface{} {
+	return (*b)[len(*b)-1].ID
+}
+
+func (b *revisionBatch) insert(ctx context.Context, db bun.IDB) error {
+	_, err := db.NewInsert().Model(b).Exec(ctx)
+	return err
+}
+
+func (b *revisionBatch) hash(sum *checksum) error {
+	for _, r := range *b {
+		before, err := hashJSON(r.Before)
+		if err != nil {
+			return fmt.Errorf("invalid before of the revision %d, %w", r.ID, err)
+		}
+		after, err := hashJSON(r.After)
+		if err != nil {
+			return fmt.Errorf("invalid after of the revision %d, %w", r.ID, err)
+		}
+		sum.add(strconv.Itoa(r.ID), strconv.Itoa(r.FruitID), strconv.Itoa(r.Version), string(r.Operation), r.Actor,
+			hashTime(r.CreatedAt), before, after)
+	}
+	return nil
+}
+
+type seedBatch []*SeedRecord
+
+func (b *seedBatch) read(ctx context.Context, db bun.IDB, after interface{}, limit int) error {
+	return readBatch(db.NewSelect().Model(b), "name", after, limit).Scan(ctx)
+}
+
+func (b *seedBatch) len() int {
+	return len(*b)
+}
+
+func (b *seedBatch) last() interface{} {
+	return (*b)[len(*b)-1].Name
+}
+
+func (b *seedBatch) insert(ctx context.Context, db bun.IDB) error {
+	_, err := db.NewInsert().Model(b).Exec(ctx)
+	return err
+}
+
+func (b *seedBatch) hash(sum *checksum) error {
+	for _, s := range *b {
+		sum.add(s.Name, s.Hash, hashTime(s.AppliedAt))
+	}
+	return nil
+}
+
+// checksum is the XOR of the SHA-256 of the rows added to it, so that it does
+// not depend on the order the databases return the rows in
+type checksum [sha256.Size]byte
+
+// add adds the row having the values to the checksum
+func (c *checksum) add(values ...string) {
+	h := sha256.New()
+	for _, v := range values {
+		//the quoting keeps the values apart
+		io.WriteString(h, strconv.Quote(v))
+	}
+	for i, b := range h.Sum(nil) {
+		c[i] ^= b
+	}
+}
+
+func (c *checksum) String() string {
+	return hex.EncodeToString(c[:])
+}
+
+// hashTime formats the time as it is compared by the checksums, rounded to
+// the second like mysql does
+func hashTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Round(time.Second).Format(time.RFC3339)
+}
+
+func hashTimePtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return hashTime(*t)
+}
+
+// hashJSON formats the JSON as it is compared by the checksums, pgsql and
+// mysql reorder the keys of the JSON objects and drop their spaces
+func hashJSON(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return "", err
+	}
+	//the keys of the maps are marshaled sorted
+	b, err := json.Marshal(v)
+	return string(b), err
+}
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyTo(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	from := newSeedDB(t, ctx)
+	if _, err := from.Seed(ctx, "data", []byte(seedData), SeedOptions{Mode: SeedIfNew, Validator: seasonValidator{}}); err != nil {
+		t.Fatal(err)
+	}
+	fromRepo := NewBunFruitRepository(from)
+	kiwi := &Fruit{Name: "Kiwi", Season: "Winter"}
+	if err := fromRepo.Create(ctx, kiwi); err != nil {
+		t.Fatal(err)
+	}
+	kiwi.Season = "Fall"
+	if err := fromRepo.Update(WithActor(ctx, "alice"), kiwi); err != nil {
+		t.Fatal(err)
+	}
+	if err := fromRepo.Delete(ctx, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	to := newSeedDB(t, ctx, WithAutoMigrate(false))
+	progress := map[string][]int{}
+	copied, err := from.CopyTo(ctx, to, CopyOptions{
+		BatchSize: 2,
+		Progress: func(table string, n int) {
+			progress[table] = append(progress[table], n)
+		},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	if assert.Len(t, copied, 3) {
+		assert.Equal(t, "fruits", copied[0].Name)
+		assert.Equal(t, 3, copied[0].Rows)
+		assert.Equal(t, "fruit_revisions", copied[1].Name)
+		assert.Equal(t, 5, copied[1].Rows)
+		assert.Equal(t, "seeds", copied[2].Name)
+		assert.Equal(t, 1, copied[2].Rows)
+		for _, ct := range copied {
+			assert.Len(t, ct.Checksum, 64)
+		}
+	}
+	assert.Equal(t, map[string][]int{"fruits": {2, 3}, "fruit_revisions": {2, 4, 5}, "seeds": {1}}, progress)
+
+	toRepo := NewBunFruitRepository(to)
+	for _, deleted := range []bool{false, true} {
+		list := fromRepo.List
+		if deleted {
+			list = fromRepo.ListDeleted
+		}
+		want, _, err := list(ctx, ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		list = toRepo.List
+		if deleted {
+			list = toRepo.ListDeleted
+		}
+		got, _, err := list(ctx, ListOptions{})
+		if assert.NoError(t, err) && assert.Len(t, got, len(want)) {
+			for i, f := range want {
+				assert.Equal(t, f.ID, got[i].ID, "Expecting the ids to be kept")
+				assert.Equal(t, f.Name, got[i].Name)
+				assert.Equal(t, f.Version, got[i].Version)
+				assert.True(t, f.CreatedAt.Equal(got[i].CreatedAt), "Expecting the creation time %s to be kept but got %s", f.CreatedAt, got[i].CreatedAt)
+			}
+		}
+	}
+	history, err := toRepo.History(ctx, kiwi.ID)
+	if assert.NoError(t, err) && assert.Len(t, history, 2) {
+		assert.Equal(t, "alice", history[1].Actor)
+	}
+	seeds, err := to.SeedRecords(ctx)
+	if assert.NoError(t, err) && assert.Len(t, seeds, 1) {
+		assert.Equal(t, "data", seeds[0].Name)
+	}
+
+	//the next fruit follows the copied ones
+	f := &Fruit{Name: "Fig", Season: "Summer"}
+	if assert.NoError(t, toRepo.Create(ctx, f)) {
+		assert.Equal(t, kiwi.ID+1, f.ID)
+	}
+
+	//the target has to be empty
+	_, err = from.CopyTo(ctx, to, CopyOptions{})
+	assert.EqualError(t, err, "the fruits table of the target database has 4 rows, copy to an empty database")
+}
+
+func TestCopyToPendingMigrations(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	from := newSeedDB(t, ctx, WithAutoMigrate(false))
+	to := newSeedDB(t, ctx, WithAutoMigrate(false))
+	_, err := from.CopyTo(ctx, to, CopyOptions{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "pending migrations, run 'migrate up' on it first")
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	createdAt := time.Date(2023, 1, 10, 9, 30, 0, 700_000_000, time.UTC)
+	before, err := hashJSON(json.RawMessage(`{"name": "Mango", "id": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := hashJSON(json.RawMessage(`{"id":1,"name":"Mango"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, before, after, "Expecting the JSON to be compared regardless of the key order and the spaces")
+
+	var a, b checksum
+	a.add("1", "Mango", hashTime(createdAt))
+	a.add("2", "Kiwi", "")
+	//mysql rounds the fractions of the seconds
+	b.add("2", "Kiwi", "")
+	b.add("1", "Mango", hashTime(createdAt.Round(time.Second).In(time.FixedZone("IST", 19800))))
+	assert.Equal(t, a.String(), b.String(), "Expecting the checksum not to depend on the order of the rows")
+
+	b.add("3", "Fig", "")
+	assert.NotEqual(t, a.String(), b.String())
+	var c checksum
+	c.add("1", "Mango Spring")
+	var d checksum
+	d.add("1", "Mango", " Spring")
+	assert.NotEqual(t, c.String(), d.String(), "Expecting the values to be kept apart")
+}
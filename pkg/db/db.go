@@ -3,12 +3,12 @@ package db
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
-	"os"
+	"net"
 	"sync"
 	"time"
 
-	"github.com/labstack/gommon/log"
 	"github.com/sirupsen/logrus"
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/dialect"
@@ -18,10 +18,9 @@ import (
 	"github.com/uptrace/bun/dialect/sqlitedialect"
 	"github.com/uptrace/bun/driver/pgdriver"
 	"github.com/uptrace/bun/driver/sqliteshim"
-	"github.com/uptrace/bun/extra/bundebug"
+	"github.com/uptrace/bun/extra/bunotel"
 
-	// used to import mysql drivers
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 // Config configures the database to initialize
@@ -31,8 +30,66 @@ type Config struct {
 	DBFile string
 	DB     *bun.DB
 	DBType dialect.Name
+	// AutoMigrate applies the pending schema migrations on Init
+	AutoMigrate bool
+	// DialTimeout is the timeout to connect to the pgsql and mysql databases
+	DialTimeout time.Duration
+	// ReadTimeout is the timeout to read from the pgsql and mysql database connections
+	ReadTimeout time.Duration
+	// WriteTimeout is the timeout to write to the pgsql and mysql database connections
+	WriteTimeout time.Duration
+	// Postgres is where to connect to the pgsql database
+	Postgres Postgres
+	// MySQL is where to connect to the mysql database
+	MySQL MySQL
+	// TLS configures the encryption of the pgsql and mysql connections
+	TLS TLS
+	// Pool configures the connection pool of the database
+	Pool Pool
+	// Retry configures the attempts to reach the database on Init
+	Retry Retry
 }
 
+// Pool configures the connection pool, see sql.DB for the meaning of the zero values
+type Pool struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+const (
+	// DefaultMaxIdleConns is the default number of idle connections kept in the pool
+	DefaultMaxIdleConns = 2
+	// DefaultConnMaxLifetime is the default time after which the connections are
+	// reopened, it is below the timeouts of the servers and the load balancers
+	DefaultConnMaxLifetime = 3 * time.Minute
+)
+
+// Postgres configures the connection to the pgsql database
+type Postgres struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Database string
+}
+
+// MySQL configures the connection to the mysql database
+type MySQL struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Database string
+	// Protocol is either tcp or unix, the Host is the path of the socket for the latter
+	Protocol string
+}
+
+// DefaultNetworkTimeout is the default dial, read and write timeout
+// of the pgsql and mysql database connections
+const DefaultNetworkTimeout = 5 * time.Second
+
 type Option func(*Config)
 
 func WithLogger(log *logrus.Logger) Option {
@@ -50,6 +107,65 @@ func WithDBFile(dbFile string) Option {
 	}
 }
 
+// WithAutoMigrate enables or disables applying the pending schema migrations on Init,
+// defaults to true
+func WithAutoMigrate(autoMigrate bool) Option {
+	return func(c *Config) {
+		c.AutoMigrate = autoMigrate
+	}
+}
+
+// WithTimeouts sets the dial, read and write timeouts of the pgsql and mysql
+// database connections, a zero timeout keeps the DefaultNetworkTimeout
+func WithTimeouts(dial, read, write time.Duration) Option {
+	return func(c *Config) {
+		if dial > 0 {
+			c.DialTimeout = dial
+		}
+		if read > 0 {
+			c.ReadTimeout = read
+		}
+		if write > 0 {
+			c.WriteTimeout = write
+		}
+	}
+}
+
+// WithPostgres sets where to connect to the pgsql database
+func WithPostgres(pg Postgres) Option {
+	return func(c *Config) {
+		c.Postgres = pg
+	}
+}
+
+// WithMySQL sets where to connect to the mysql database
+func WithMySQL(my MySQL) Option {
+	return func(c *Config) {
+		c.MySQL = my
+	}
+}
+
+// WithTLS sets how the pgsql and mysql connections are encrypted
+func WithTLS(t TLS) Option {
+	return func(c *Config) {
+		c.TLS = t
+	}
+}
+
+// WithPool sets the connection pool settings of the database
+func WithPool(p Pool) Option {
+	return func(c *Config) {
+		c.Pool = p
+	}
+}
+
+// WithRetry sets how long and how often Init attempts to reach the database
+func WithRetry(r Retry) Option {
+	return func(c *Config) {
+		c.Retry = r
+	}
+}
+
 func WithDBType(dbType string) Option {
 	return func(c *Config) {
 		switch dbType {
@@ -57,7 +173,6 @@ func WithDBType(dbType string) Option {
 			c.DBType = dialect.PG
 		case "mysql":
 			c.DBType = dialect.MySQL
-		case "sqlite":
 		default:
 			c.DBType = dialect.SQLite
 		}
@@ -66,7 +181,20 @@ func WithDBType(dbType string) Option {
 
 // New creates a new instance of Config to create and initialize new database
 func New(options ...Option) *Config {
-	cfg := &Config{}
+	cfg := &Config{
+		AutoMigrate:  true,
+		DialTimeout:  DefaultNetworkTimeout,
+		ReadTimeout:  DefaultNetworkTimeout,
+		WriteTimeout: DefaultNetworkTimeout,
+		Postgres:     Postgres{Host: "localhost", Port: "5432"},
+		MySQL:        MySQL{Host: "localhost", Port: "3306", Protocol: "tcp"},
+		TLS:          TLS{Mode: TLSDisable},
+		Pool: Pool{
+			MaxIdleConns:    DefaultMaxIdleConns,
+			ConnMaxLifetime: DefaultConnMaxLifetime,
+		},
+		Retry: DefaultRetry,
+	}
 	for _, o := range options {
 		o(cfg)
 	}
@@ -74,134 +202,101 @@ func New(options ...Option) *Config {
 	return cfg
 }
 
-// Init initializes the database with the given configuration
-func (c *Config) Init(ctx context.Context) {
+// Init initializes the database with the given configuration, the database
+// is attempted to be reached until the Retry timeout elapses
+func (c *Config) Init(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	log := c.Log
 	log.Infof("Initializing DB of type %s", c.DBType)
 	switch c.DBType {
 	case dialect.PG:
-		pgConn := buildPGConnector()
-		sqldb := sql.OpenDB(pgConn)
-		c.DB = bun.NewDB(sqldb, pgdialect.New())
+		pgConn, err := c.buildPGConnector()
+		if err != nil {
+			return err
+		}
+		c.DB = bun.NewDB(sql.OpenDB(pgConn), pgdialect.New())
 	case dialect.MySQL:
-		sqldb, err := sql.Open("mysql", buildMYSQLDSN())
+		myConn, err := c.buildMySQLConnector()
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-		c.DB = bun.NewDB(sqldb, mysqldialect.New())
-		c.DB.SetConnMaxLifetime(time.Minute * 3)
-		c.DB.SetMaxOpenConns(3)
-		c.DB.SetMaxIdleConns(1)
+		c.DB = bun.NewDB(sql.OpenDB(myConn), mysqldialect.New())
 	default:
 		sqlite, err := sql.Open(sqliteshim.ShimName, fmt.Sprintf("file:%s?cache=shared", c.DBFile))
 		if err != nil {
-			log.Fatal(err)
+			return fmt.Errorf("unable to open the database, %w", err)
 		}
 		c.DB = bun.NewDB(sqlite, sqlitedialect.New())
 	}
+	c.DB.SetMaxOpenConns(c.Pool.MaxOpenConns)
+	c.DB.SetMaxIdleConns(c.Pool.MaxIdleConns)
+	c.DB.SetConnMaxLifetime(c.Pool.ConnMaxLifetime)
+	c.DB.SetConnMaxIdleTime(c.Pool.ConnMaxIdleTime)
 
-	if err := c.DB.Ping(); err != nil {
-		log.Fatal(err)
+	if err := c.Retry.Do(ctx, log, "connect to the database", c.DB.PingContext); err != nil {
+		c.DB.Close()
+		return err
 	}
 
-	isVerbose := log.Level == logrus.DebugLevel || log.Level == logrus.TraceLevel
-	c.DB.AddQueryHook(bundebug.NewQueryHook(
-		bundebug.WithVerbose(isVerbose),
-		bundebug.WithVerbose(isVerbose),
-	))
+	//the tracing hook goes first so that the query logs have the span of the query
+	c.DB.AddQueryHook(bunotel.NewQueryHook())
+	c.DB.AddQueryHook(&queryLogger{log: log})
 
 	//Setup Schema
-	if err := c.createTables(ctx); err != nil {
-		log.Errorf("%s", err)
-	}
-}
-
-func (c *Config) createTables(ctx context.Context) error {
-	//Fruits
-	if _, err := c.DB.NewCreateTable().
-		Model((*Fruit)(nil)).
-		IfNotExists().
-		Exec(ctx); err != nil {
-		return err
+	if c.AutoMigrate {
+		if _, err := c.migrate(ctx, ""); err != nil {
+			c.DB.Close()
+			return err
+		}
 	}
 	return nil
 }
 
-func buildPGConnector() *pgdriver.Connector {
-	var (
-		pgHost     = "localhost"
-		pgPort     = "5432"
-		pgUser     = "demo"
-		pgPassword = "pa55Word!"
-		pgDatabase = "demodb"
-	)
-
-	if h, ok := os.LookupEnv("POSTGRES_HOST"); ok {
-		pgHost = h
-	}
-	if p, ok := os.LookupEnv("POSTGRES_PORT"); ok {
-		pgPort = p
+func (c *Config) buildPGConnector() (*pgdriver.Connector, error) {
+	pg := c.Postgres
+	tlsConfig, err := c.TLS.Config(pg.Host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS settings of the pgsql database, %w", err)
 	}
-	if h, ok := os.LookupEnv("POSTGRES_USER"); ok {
-		pgUser = h
-	}
-	if p, ok := os.LookupEnv("POSTGRES_PASSWORD"); ok {
-		pgPassword = p
-	}
-	if d, ok := os.LookupEnv("POSTGRES_DB"); ok {
-		pgDatabase = d
-	}
-
 	pgConn := pgdriver.NewConnector(
 		pgdriver.WithNetwork("tcp"),
-		pgdriver.WithAddr(fmt.Sprintf("%s:%s", pgHost, pgPort)),
-		pgdriver.WithPassword(pgPassword),
-		pgdriver.WithUser(pgUser),
-		pgdriver.WithDatabase(pgDatabase),
-		pgdriver.WithTLSConfig(nil),
-		pgdriver.WithTimeout(5*time.Second),
-		pgdriver.WithDialTimeout(5*time.Second),
-		pgdriver.WithReadTimeout(5*time.Second),
-		pgdriver.WithWriteTimeout(5*time.Second),
+		pgdriver.WithAddr(net.JoinHostPort(pg.Host, pg.Port)),
+		pgdriver.WithPassword(pg.Password),
+		pgdriver.WithUser(pg.User),
+		pgdriver.WithDatabase(pg.Database),
+		pgdriver.WithTLSConfig(tlsConfig),
+		pgdriver.WithDialTimeout(c.DialTimeout),
+		pgdriver.WithReadTimeout(c.ReadTimeout),
+		pgdriver.WithWriteTimeout(c.WriteTimeout),
 		pgdriver.WithApplicationName("fruits-api"),
 	)
 
-	log.Infof("PostgresSQL Address %s", pgConn.Config().Addr)
+	c.Log.Infof("PostgresSQL Address %s, TLS %s", pgConn.Config().Addr, c.TLS.Mode)
 
-	return pgConn
+	return pgConn, nil
 }
 
-func buildMYSQLDSN() string {
-	var (
-		mySQLHost     = "localhost"
-		mySQLPort     = "3306"
-		mySQLUser     = "demo"
-		mySQLPassword = "pa55Word!"
-		mySQLDatabase = "demodb"
-		mySQLProtocol = "tcp"
-	)
-
-	if h, ok := os.LookupEnv("MYSQL_HOST"); ok {
-		mySQLHost = h
-	}
-	if p, ok := os.LookupEnv("MYSQL_PORT"); ok {
-		mySQLPort = p
-	}
-	if h, ok := os.LookupEnv("MYSQL_USER"); ok {
-		mySQLUser = h
+func (c *Config) buildMySQLConnector() (driver.Connector, error) {
+	my := mysql.NewConfig()
+	my.User = c.MySQL.User
+	my.Passwd = c.MySQL.Password
+	my.Net = c.MySQL.Protocol
+	my.Addr = net.JoinHostPort(c.MySQL.Host, c.MySQL.Port)
+	if my.Net == "unix" {
+		my.Addr = c.MySQL.Host
 	}
-	if p, ok := os.LookupEnv("MYSQL_PASSWORD"); ok {
-		mySQLPassword = p
-	}
-	if d, ok := os.LookupEnv("MYSQL_DB"); ok {
-		mySQLDatabase = d
-	}
-	if d, ok := os.LookupEnv("MYSQL_PROTOCOL"); ok {
-		mySQLProtocol = d
+	my.DBName = c.MySQL.Database
+	my.Timeout = c.DialTimeout
+	my.ReadTimeout = c.ReadTimeout
+	my.WriteTimeout = c.WriteTimeout
+	tlsConfig, err := c.TLS.Config(c.MySQL.Host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS settings of the mysql database, %w", err)
 	}
+	my.TLS = tlsConfig
+
+	c.Log.Infof("MySQL Address %s, TLS %s", my.Addr, c.TLS.Mode)
 
-	return fmt.Sprintf("%s:%s@%s(%s:%s)/%s",
-		mySQLUser, mySQLPassword, mySQLProtocol, mySQLHost, mySQLPort, mySQLDatabase)
+	return mysql.NewConnector(my)
 }
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+const (
+	// trashMigration adds the deleted_at column of the fruits
+	trashMigration = "20221215000000"
+	// dedupeMigration is the last migration the dedupe needs, the fruits have
+	// their deleted_at and version columns once it is applied
+	dedupeMigration = "20221220000000"
+	// historyMigration creates the fruit history, which the dedupe doesn't record
+	historyMigration = "20230105000000"
+)
+
+// DuplicateGroup are the fruits having the same name ignoring the case
+type DuplicateGroup struct {
+	// Name is the name of the kept fruit
+	Name string
+	// Kept is the id of the fruit that is kept, the oldest one of the group
+	Kept int
+	// Removed are the ids of the duplicates that are moved to the trash
+	Removed []int
+}
+
+// Dedupe moves the fruits whose name duplicates the name of an older fruit,
+// ignoring the case, to the trash. It is meant to be run once on databases that
+// were populated before the fruit names had to be unique, the kept fruits get the
+// emoji of their duplicates when they have none. When dryRun is true the duplicates
+// are only reported. The migrations the dedupe needs are applied before the
+// duplicates are removed, it refuses to run once the fruit history migration is
+// applied as the removed duplicates would not be recorded in the history.
+func (c *Config) Dedupe(ctx context.Context, dryRun bool) ([]DuplicateGroup, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m, err := c.Migrator()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Init(ctx); err != nil {
+		return nil, err
+	}
+	ms, err := m.AppliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := map[string]bool{}
+	for _, m := range ms {
+		applied[m.Name] = true
+	}
+	if applied[historyMigration] {
+		return nil, errors.New("the fruit history migration is applied, the fruit names are already unique")
+	}
+	if !dryRun {
+		if _, err := c.migrate(ctx, dedupeMigration); err != nil {
+			return nil, err
+		}
+		applied[trashMigration] = true
+	}
+
+	var groups []DuplicateGroup
+	err = c.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		var fruits Fruits
+		//a database populated before the migrations has only these columns
+		q := tx.NewSelect().
+			Model(&fruits).
+			Column("id", "name", "emoji").
+			OrderExpr("? ASC", bun.Ident("id"))
+		if !applied[trashMigration] {
+			q = q.WhereAllWithDeleted()
+		}
+		if err := q.Scan(ctx); err != nil {
+			return err
+		}
+		kept := map[string]*Fruit{}
+		index := map[string]int{}
+		emojiFilled := map[int]bool{}
+		for _, f := range fruits {
+			key := strings.ToUpper(f.Name)
+			k, ok := kept[key]
+			if !ok {
+				kept[key] = f
+				continue
+			}
+			i, ok := index[key]
+			if !ok {
+				i = len(groups)
+				index[key] = i
+				groups = append(groups, DuplicateGroup{Name: k.Name, Kept: k.ID})
+			}
+			groups[i].Removed = append(groups[i].Removed, f.ID)
+			if k.Emoji == "" && f.Emoji != "" {
+				k.Emoji = f.Emoji
+				emojiFilled[k.ID] = true
+			}
+		}
+		if dryRun {
+			return nil
+		}
+		for _, g := range groups {
+			k := kept[strings.ToUpper(g.Name)]
+			if _, err := tx.NewDelete().
+				Model((*Fruit)(nil)).
+				Where("? IN (?)", bun.Ident("id"), bun.In(g.Removed)).
+				Exec(ctx); err != nil {
+				return err
+			}
+			if !emojiFilled[k.ID] {
+				continue
+			}
+			if _, err := tx.NewUpdate().
+				Model((*Fruit)(nil)).
+				Set("? = ?", bun.Ident("emoji"), k.Emoji).
+				Set("? = ?", bun.Ident("modified_at"), time.Now()).
+				Set("? = ? + 1", bun.Ident("version"), bun.Ident("version")).
+				Where("? = ?", bun.Ident("id"), k.ID).
+				Exec(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
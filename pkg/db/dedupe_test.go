@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupe(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	log := utils.LogSetup(os.Stdout, utils.LookupEnvOrString("TEST_LOG_LEVEL", "info"))
+	dbc := New(
+		WithLogger(log),
+		WithDBType("sqlite"),
+		WithDBFile(path.Join(t.TempDir(), "dedupe.db")),
+		WithAutoMigrate(false))
+	if err := dbc.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	//a database populated before the migrations and the unique names
+	if _, err := dbc.DB.ExecContext(ctx, `CREATE TABLE "fruits" (
+  "id" INTEGER NOT NULL,
+  "name" VARCHAR NOT NULL,
+  "season" VARCHAR NOT NULL,
+  "emoji" VARCHAR,
+  "created_at" TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  "modified_at" TIMESTAMP,
+  PRIMARY KEY ("id")
+)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbc.DB.ExecContext(ctx, `INSERT INTO "fruits" ("name", "season", "emoji") VALUES
+  ('Mango', 'Spring', NULL),
+  ('Apple', 'Fall', NULL),
+  ('MANGO', 'Summer', 'U+1F96D'),
+  ('mango', 'Summer', NULL),
+  ('apple', 'Fall', NULL),
+  ('Pear', 'Fall', NULL)`); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []DuplicateGroup{
+		{Name: "Mango", Kept: 1, Removed: []int{3, 4}},
+		{Name: "Apple", Kept: 2, Removed: []int{5}},
+	}
+	groups, err := dbc.Dedupe(ctx, true)
+	if assert.NoError(t, err) {
+		assert.Equal(t, want, groups)
+	}
+	n, err := dbc.DB.NewSelect().Table("fruits").Count(ctx)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 6, n, "Expecting a dry run to keep the duplicates")
+	}
+	applied, err := dbc.MigrationStatus(ctx)
+	if assert.NoError(t, err) {
+		assert.Empty(t, applied.Applied(), "Expecting a dry run not to migrate the database")
+	}
+
+	groups, err = dbc.Dedupe(ctx, false)
+	if assert.NoError(t, err) {
+		assert.Equal(t, want, groups)
+	}
+	repo := NewBunFruitRepository(dbc)
+	active, _, err := repo.List(ctx, ListOptions{})
+	if assert.NoError(t, err) {
+		assert.ElementsMatch(t, []string{"Mango", "Apple", "Pear"}, names(active))
+	}
+	mango, err := repo.Get(ctx, 1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "U+1F96D", mango.Emoji, "Expecting the kept fruit to get the emoji of its duplicate")
+		assert.Equal(t, 2, mango.Version)
+	}
+	_, total, err := repo.ListDeleted(ctx, ListOptions{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3, total, "Expecting the duplicates to be in the trash")
+	}
+
+	_, err = dbc.Migrate(ctx)
+	assert.NoError(t, err, "Expecting the unique names migration to be applied once the duplicates are removed")
+
+	_, err = dbc.Dedupe(ctx, true)
+	assert.EqualError(t, err, "the fruit history migration is applied, the fruit names are already unique")
+}
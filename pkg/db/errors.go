@@ -0,0 +1,48 @@
+package db
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+const (
+	// fruitNameIndex is the unique index of the normalized fruit names
+	fruitNameIndex = "fruits_name_uidx"
+	// mySQLDuplicateEntry is the MySQL error number of the unique key violations
+	mySQLDuplicateEntry = 1062
+)
+
+// isUniqueViolation reports whether the err is the unique constraint violation
+// error of one of the supported databases, when index is not empty the
+// violated constraint must be that index
+func isUniqueViolation(err error, index string) bool {
+	var pgErr pgdriver.Error
+	if errors.As(err, &pgErr) {
+		return pgErr.Field('C') == "23505" && (index == "" || pgErr.Field('n') == index)
+	}
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == mySQLDuplicateEntry && strings.Contains(myErr.Message, index)
+	}
+	//both the sqlite drivers of sqliteshim use the sqlite message
+	msg := err.Error()
+	return strings.Contains(msg, "UNIQUE constraint failed") && strings.Contains(msg, index)
+}
+
+// fruitConflict maps the unique constraint violations of the fruit f to
+// apperrors.ConflictError, the other errors are returned as is
+func fruitConflict(err error, f *Fruit) error {
+	switch {
+	case err == nil:
+		return nil
+	case isUniqueViolation(err, fruitNameIndex):
+		return apperrors.Conflict("fruit %q already exists", f.Name)
+	case isUniqueViolation(err, ""):
+		return apperrors.Conflict("fruit with id %d already exists", f.ID)
+	}
+	return err
+}
@@ -12,9 +12,14 @@ var _ bun.BeforeAppendModelHook = (*Fruit)(nil)
 
 // BeforeAppendModel implements schema.BeforeAppendModelHook
 func (f *Fruit) BeforeAppendModel(ctx context.Context, query schema.Query) error {
+	//the seasons are validated ignoring the case, they are saved the way they are listed
+	f.Season = CanonicalSeason(f.Season)
 	switch query.(type) {
 	case *bun.InsertQuery:
 		f.CreatedAt = time.Now()
+		if f.Version == 0 {
+			f.Version = 1
+		}
 	case *bun.UpdateQuery:
 		f.ModifiedAt = time.Now()
 	}
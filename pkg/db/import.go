@@ -0,0 +1,195 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// MaxImportReport is the maximum number of changes and of rejections listed
+// by an ImportResult, the counts include all of them
+const MaxImportReport = 1000
+
+// ImportAction is what importing a row does
+type ImportAction string
+
+const (
+	// ImportInsert adds the fruit of the row
+	ImportInsert ImportAction = "insert"
+	// ImportUpdate sets the fruit having the name of the row to the row
+	ImportUpdate ImportAction = "update"
+	// ImportSkip leaves the fruit having the name of the row but other values
+	// alone, as the import does not update the fruits
+	ImportSkip ImportAction = "skip"
+	// ImportUnchanged is the row that is identical to the fruit having its name
+	ImportUnchanged ImportAction = "unchanged"
+)
+
+// errImportDryRun rolls back the transaction of a dry-run import
+var errImportDryRun = errors.New("dry-run import")
+
+// ImportRow is a fruit read from an import
+type ImportRow struct {
+	// Line is where the row starts in the input
+	Line  int
+	Fruit *Fruit
+	// Err is the reason the row could not be read, the row is rejected
+	Err error
+}
+
+// ImportReader reads the rows of an import one at a time so that the input
+// does not need to fit in memory
+type ImportReader interface {
+	// Next returns the next row, io.EOF after the last one. Any other
+	// error means the input could not be read any further.
+	Next() (*ImportRow, error)
+}
+
+// ImportOptions configures how an import is applied
+type ImportOptions struct {
+	// Update sets the fruits having the names of the rows to them, otherwise
+	// the rows whose names are taken are skipped
+	Update bool
+	// DryRun reports what the import would do without changing anything
+	DryRun bool
+	// Validator validates each row like the fruits added with the API, the
+	// invalid rows are rejected
+	Validator interface {
+		Validate(i interface{}) error
+	}
+}
+
+// ImportValues are the values of a fruit that an import sets
+type ImportValues struct {
+	Name   string `json:"name" example:"Mango"`
+	Season string `json:"season" example:"Spring"`
+	Emoji  string `json:"emoji,omitempty" example:"U+1F96D"`
+}
+
+// ImportChange is the change a row makes, or would make with a dry-run
+type ImportChange struct {
+	Line   int          `json:"line" example:"2"`
+	Action ImportAction `json:"action" enums:"insert,update,skip" example:"update"`
+	// ID is the id of the fruit, it is not set for the inserts of a dry-run
+	ID int `json:"id,omitempty" example:"1"`
+	// Before is the fruit having the name of the row, it is empty for the inserts
+	Before *ImportValues `json:"before,omitempty"`
+	After  ImportValues  `json:"after"`
+}
+
+// ImportRejection is a row that could not be imported
+type ImportRejection struct {
+	Line  int    `json:"line" example:"3"`
+	Error string `json:"error" example:"validation failed, season must be one of Spring,Summer,Fall,Winter"`
+}
+
+// ImportResult tells what an import did, or would do with a dry-run
+type ImportResult struct {
+	DryRun    bool `json:"dry_run"`
+	Inserted  int  `json:"inserted"`
+	Updated   int  `json:"updated"`
+	Skipped   int  `json:"skipped"`
+	Unchanged int  `json:"unchanged"`
+	Rejected  int  `json:"rejected"`
+	// Changes are the first MaxImportReport inserts, updates and skips
+	Changes []ImportChange `json:"changes"`
+	// Rejections are the first MaxImportReport rejected rows
+	Rejections []ImportRejection `json:"rejections"`
+}
+
+func newImportResult(dryRun bool) *ImportResult {
+	return &ImportResult{
+		DryRun:     dryRun,
+		Changes:    []ImportChange{},
+		Rejections: []ImportRejection{},
+	}
+}
+
+// importAction returns what importing the fruit f does to the fruit existing
+// having its name, existing is nil when there is none
+func importAction(existing *Fruit, f *Fruit, update bool) ImportAction {
+	switch {
+	case existing == nil:
+		return ImportInsert
+	case existing.Name == f.Name && existing.Season == f.Season && existing.Emoji == f.Emoji:
+		return ImportUnchanged
+	case update:
+		return ImportUpdate
+	default:
+		return ImportSkip
+	}
+}
+
+// importRows reads the rows of rd and hands the valid ones to apply, the
+// rows that could not be read or are invalid are rejected. The error of apply
+// stops the import.
+func importRows(ctx context.Context, rd ImportReader, opts ImportOptions, res *ImportResult, apply func(row *ImportRow) error) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		row, err := rd.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if row.Err == nil && opts.Validator != nil {
+			row.Err = opts.Validator.Validate(row.Fruit)
+		}
+		if row.Err != nil {
+			res.reject(row.Line, row.Err)
+			continue
+		}
+		//the season is compared to the saved one the way it is saved
+		row.Fruit.Season = CanonicalSeason(row.Fruit.Season)
+		if err := apply(row); err != nil {
+			return err
+		}
+	}
+}
+
+// record counts the action applied to the row, existing is the fruit having
+// the name of the row before it
+func (res *ImportResult) record(row *ImportRow, action ImportAction, existing *Fruit) {
+	switch action {
+	case ImportInsert:
+		res.Inserted++
+	case ImportUpdate:
+		res.Updated++
+	case ImportSkip:
+		res.Skipped++
+	case ImportUnchanged:
+		res.Unchanged++
+		return
+	}
+	if len(res.Changes) >= MaxImportReport {
+		return
+	}
+	change := ImportChange{
+		Line:   row.Line,
+		Action: action,
+		After:  importValues(row.Fruit),
+	}
+	if existing != nil {
+		change.ID = existing.ID
+		before := importValues(existing)
+		change.Before = &before
+	} else if !res.DryRun {
+		change.ID = row.Fruit.ID
+	}
+	res.Changes = append(res.Changes, change)
+}
+
+// reject counts the row at the line as rejected for err
+func (res *ImportResult) reject(line int, err error) {
+	res.Rejected++
+	if len(res.Rejections) < MaxImportReport {
+		res.Rejections = append(res.Rejections, ImportRejection{Line: line, Error: err.Error()})
+	}
+}
+
+func importValues(f *Fruit) ImportValues {
+	return ImportValues{Name: f.Name, Season: f.Season, Emoji: f.Emoji}
+}
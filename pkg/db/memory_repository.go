@@ -0,0 +1,457 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+	"github.com/kameshsampath/go-fruits-api/pkg/query"
+)
+
+// MemoryFruitRepository is the thread-safe FruitRepository that keeps the
+// fruits in memory, it is meant for tests and demos
+type MemoryFruitRepository struct {
+	mu        sync.RWMutex
+	fruits    map[int]*Fruit
+	lastID    int
+	revisions FruitRevisions
+}
+
+var _ FruitRepository = (*MemoryFruitRepository)(nil)
+
+// NewMemoryFruitRepository creates the in-memory FruitRepository holding the fruits
+func NewMemoryFruitRepository(fruits ...*Fruit) *MemoryFruitRepository {
+	r := &MemoryFruitRepository{
+		fruits: map[int]*Fruit{},
+	}
+	for _, f := range fruits {
+		//a new repository could not fail to create
+		_ = r.Create(context.Background(), f)
+	}
+	return r
+}
+
+// Create implements FruitRepository
+func (r *MemoryFruitRepository) Create(ctx context.Context, f *Fruit) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.create(ctx, f)
+}
+
+func (r *MemoryFruitRepository) create(ctx context.Context, f *Fruit) error {
+	if _, ok := r.fruits[f.ID]; ok {
+		return apperrors.Conflict("fruit with id %d already exists", f.ID)
+	}
+	if err := r.checkNameAvailable(f.Name, f.ID); err != nil {
+		return err
+	}
+	if f.ID == 0 {
+		f.ID = r.lastID + 1
+	}
+	if f.ID > r.lastID {
+		r.lastID = f.ID
+	}
+	f.Season = CanonicalSeason(f.Season)
+	f.CreatedAt = time.Now()
+	if f.Version == 0 {
+		f.Version = 1
+	}
+	r.fruits[f.ID] = copyFruit(f)
+	return r.record(ctx, RevisionInsert, nil, f)
+}
+
+// Get implements FruitRepository
+func (r *MemoryFruitRepository) Get(ctx context.Context, id int) (*Fruit, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f, ok := r.fruits[id]
+	if !ok || f.DeletedAt != nil {
+		return nil, apperrors.NotFound("fruit", id)
+	}
+	return copyFruit(f), nil
+}
+
+// List implements FruitRepository
+func (r *MemoryFruitRepository) List(ctx context.Context, opts ListOptions) (Fruits, int, error) {
+	return r.list(opts, false)
+}
+
+// ListDeleted implements FruitRepository
+func (r *MemoryFruitRepository) ListDeleted(ctx context.Context, opts ListOptions) (Fruits, int, error) {
+	return r.list(opts, true)
+}
+
+// list lists the fruits that are not deleted or the ones in the trash when deleted is true
+func (r *MemoryFruitRepository) list(opts ListOptions, deleted bool) (Fruits, int, error) {
+	var after query.Expr
+	if opts.After != nil {
+		var err error
+		if after, err = opts.Sort.After(opts.After); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	r.mu.RLock()
+	matched := Fruits{}
+	for _, f := range r.fruits {
+		if (f.DeletedAt != nil) != deleted {
+			continue
+		}
+		if opts.Filter == nil || opts.Filter.Match(f) {
+			matched = append(matched, copyFruit(f))
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return opts.Sort.Less(matched[i], matched[j])
+	})
+	total := len(matched)
+	fruits := Fruits{}
+	for _, f := range matched {
+		if after == nil || after.Match(f) {
+			fruits = append(fruits, f)
+		}
+	}
+	if opts.Limit > 0 {
+		if opts.Offset >= len(fruits) {
+			return Fruits{}, total, nil
+		}
+		fruits = fruits[opts.Offset:]
+		if len(fruits) > opts.Limit {
+			fruits = fruits[:opts.Limit]
+		}
+	}
+	return fruits, total, nil
+}
+
+// Search implements FruitRepository
+func (r *MemoryFruitRepository) Search(ctx context.Context, criteria Criteria, opts ListOptions) (Fruits, int, error) {
+	opts.Filter = criteria.Filter(opts.Filter)
+	return r.List(ctx, opts)
+}
+
+// Update implements FruitRepository
+func (r *MemoryFruitRepository) Update(ctx context.Context, f *Fruit) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.update(ctx, f, RevisionUpdate)
+}
+
+func (r *MemoryFruitRepository) update(ctx context.Context, f *Fruit, op RevisionOp) error {
+	existing, ok := r.fruits[f.ID]
+	if !ok || existing.DeletedAt != nil {
+		return apperrors.NotFound("fruit", f.ID)
+	}
+	if f.Version != 0 && f.Version != existing.Version {
+		return apperrors.PreconditionFailed("fruit", f.ID)
+	}
+	if err := r.checkNameAvailable(f.Name, f.ID); err != nil {
+		return err
+	}
+	f.Season = CanonicalSeason(f.Season)
+	f.Version = existing.Version + 1
+	f.CreatedAt = existing.CreatedAt
+	f.ModifiedAt = time.Now()
+	r.fruits[f.ID] = copyFruit(f)
+	return r.record(ctx, op, existing, f)
+}
+
+// Delete implements FruitRepository
+func (r *MemoryFruitRepository) Delete(ctx context.Context, id int, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.delete(ctx, id, version)
+}
+
+func (r *MemoryFruitRepository) delete(ctx context.Context, id int, version int) error {
+	f, ok := r.fruits[id]
+	if !ok || f.DeletedAt != nil {
+		if version != 0 {
+			return apperrors.NotFound("fruit", id)
+		}
+		return nil
+	}
+	if version != 0 && version != f.Version {
+		return apperrors.PreconditionFailed("fruit", id)
+	}
+	before := copyFruit(f)
+	now := time.Now()
+	f.DeletedAt = &now
+	return r.record(ctx, RevisionDelete, before, nil)
+}
+
+// DeleteAll implements FruitRepository
+func (r *MemoryFruitRepository) DeleteAll(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, f := range r.fruits {
+		if f.DeletedAt == nil {
+			before := copyFruit(f)
+			f.DeletedAt = &now
+			if err := r.record(ctx, RevisionDelete, before, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Restore implements FruitRepository
+func (r *MemoryFruitRepository) Restore(ctx context.Context, id int) (*Fruit, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, ok := r.fruits[id]
+	if !ok || f.DeletedAt == nil {
+		return nil, apperrors.NotFound("deleted fruit", id)
+	}
+	if err := r.checkNameAvailable(f.Name, f.ID); err != nil {
+		return nil, err
+	}
+	before := copyFruit(f)
+	f.DeletedAt = nil
+	f.Version++
+	if err := r.record(ctx, RevisionRestore, before, f); err != nil {
+		return nil, err
+	}
+	return copyFruit(f), nil
+}
+
+// Purge implements FruitRepository
+func (r *MemoryFruitRepository) Purge(ctx context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for id, f := range r.fruits {
+		if f.DeletedAt != nil && f.DeletedAt.Before(before) {
+			if err := r.record(ctx, RevisionPurge, f, nil); err != nil {
+				return n, err
+			}
+			delete(r.fruits, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Batch implements FruitRepository, an atomic batch restores the fruits
+// as they were before the batch when one of the operations fails
+func (r *MemoryFruitRepository) Batch(ctx context.Context, ops []BatchOperation, atomic bool) ([]BatchResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var snapshot map[int]*Fruit
+	lastID, revisions := r.lastID, len(r.revisions)
+	if atomic {
+		snapshot = make(map[int]*Fruit, len(r.fruits))
+		for id, f := range r.fruits {
+			snapshot[id] = copyFruit(f)
+		}
+	}
+	results := make([]BatchResult, len(ops))
+	for i, op := range ops {
+		if results[i].Err = r.applyBatchOp(ctx, op, &results[i]); results[i].Err == nil {
+			continue
+		}
+		results[i].Fruit = nil
+		if atomic {
+			r.fruits, r.lastID, r.revisions = snapshot, lastID, r.revisions[:revisions]
+			rollback(results)
+			break
+		}
+	}
+	return results, nil
+}
+
+// applyBatchOp applies the batch operation op and sets its result res
+func (r *MemoryFruitRepository) applyBatchOp(ctx context.Context, op BatchOperation, res *BatchResult) error {
+	switch op.Op {
+	case BatchInsert:
+		if err := r.create(ctx, op.Fruit); err != nil {
+			return err
+		}
+		res.Fruit, res.Created = op.Fruit, true
+	case BatchUpsert:
+		var existing *Fruit
+		for _, f := range r.fruits {
+			if f.DeletedAt == nil && strings.EqualFold(f.Name, op.Fruit.Name) && (existing == nil || f.ID < existing.ID) {
+				existing = f
+			}
+		}
+		if existing == nil {
+			op.Fruit.ID = 0
+			if err := r.create(ctx, op.Fruit); err != nil {
+				return err
+			}
+			res.Fruit, res.Created = op.Fruit, true
+			return nil
+		}
+		op.Fruit.ID = existing.ID
+		op.Fruit.Version = existing.Version
+		if err := r.update(ctx, op.Fruit, RevisionUpdate); err != nil {
+			return err
+		}
+		res.Fruit = op.Fruit
+	case BatchDelete:
+		return r.delete(ctx, op.ID, 0)
+	default:
+		return unknownBatchOp(op.Op)
+	}
+	return nil
+}
+
+// Import implements FruitRepository, the fruits are restored as they were
+// before the import when it fails or with a dry-run
+func (r *MemoryFruitRepository) Import(ctx context.Context, rd ImportReader, opts ImportOptions) (*ImportResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	snapshot := make(map[int]*Fruit, len(r.fruits))
+	for id, f := range r.fruits {
+		snapshot[id] = copyFruit(f)
+	}
+	lastID, revisions := r.lastID, len(r.revisions)
+	res := newImportResult(opts.DryRun)
+	err := importRows(ctx, rd, opts, res, func(row *ImportRow) error {
+		var existing *Fruit
+		for _, f := range r.fruits {
+			if f.DeletedAt == nil && strings.EqualFold(f.Name, row.Fruit.Name) && (existing == nil || f.ID < existing.ID) {
+				existing = copyFruit(f)
+			}
+		}
+		action := importAction(existing, row.Fruit, opts.Update)
+		var err error
+		switch action {
+		case ImportInsert:
+			row.Fruit.ID = 0
+			err = r.create(ctx, row.Fruit)
+		case ImportUpdate:
+			row.Fruit.ID = existing.ID
+			row.Fruit.Version = existing.Version
+			err = r.update(ctx, row.Fruit, RevisionUpdate)
+		}
+		if err != nil {
+			res.reject(row.Line, err)
+			return nil
+		}
+		res.record(row, action, existing)
+		return nil
+	})
+	if err != nil || opts.DryRun {
+		r.fruits, r.lastID, r.revisions = snapshot, lastID, r.revisions[:revisions]
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Export implements FruitRepository, fn is called with the copies of the fruits
+// taken when the export started
+func (r *MemoryFruitRepository) Export(ctx context.Context, fn func(f *Fruit) error) error {
+	r.mu.RLock()
+	fruits := make(Fruits, 0, len(r.fruits))
+	for _, f := range r.fruits {
+		if f.DeletedAt == nil {
+			fruits = append(fruits, copyFruit(f))
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(fruits, func(i, j int) bool {
+		return fruits[i].ID < fruits[j].ID
+	})
+	for _, f := range fruits {
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountBySeason implements FruitRepository
+func (r *MemoryFruitRepository) CountBySeason(ctx context.Context) (map[string]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	counts := map[string]int{}
+	for _, f := range r.fruits {
+		if f.DeletedAt == nil {
+			counts[f.Season]++
+		}
+	}
+	return counts, nil
+}
+
+// History implements FruitRepository
+func (r *MemoryFruitRepository) History(ctx context.Context, id int) (FruitRevisions, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	revisions := FruitRevisions{}
+	for _, rev := range r.revisions {
+		if rev.FruitID == id {
+			c := *rev
+			revisions = append(revisions, &c)
+		}
+	}
+	if _, ok := r.fruits[id]; !ok && len(revisions) == 0 {
+		return nil, apperrors.NotFound("fruit", id)
+	}
+	return revisions, nil
+}
+
+// Revert implements FruitRepository
+func (r *MemoryFruitRepository) Revert(ctx context.Context, id int, revision int, version int) (*Fruit, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rev := range r.revisions {
+		if rev.ID != revision || rev.FruitID != id {
+			continue
+		}
+		f, err := revertTo(rev, version)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.update(ctx, f, RevisionRevert); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	return nil, apperrors.NotFound("revision", revision)
+}
+
+// record adds the revision of the op changing the fruit before to after
+func (r *MemoryFruitRepository) record(ctx context.Context, op RevisionOp, before *Fruit, after *Fruit) error {
+	rev, err := newRevision(ctx, op, before, after)
+	if err != nil {
+		return err
+	}
+	rev.ID = len(r.revisions) + 1
+	r.revisions = append(r.revisions, rev)
+	return nil
+}
+
+// Ping implements FruitRepository
+func (r *MemoryFruitRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+// checkNameAvailable returns apperrors.ConflictError referencing the fruit other
+// than the one with the id that already has the name ignoring the case
+func (r *MemoryFruitRepository) checkNameAvailable(name string, id int) error {
+	for _, f := range r.fruits {
+		if f.ID != id && f.DeletedAt == nil && strings.EqualFold(f.Name, name) {
+			return apperrors.AlreadyExists("fruit", f.Name, f.ID)
+		}
+	}
+	return nil
+}
+
+func copyFruit(f *Fruit) *Fruit {
+	c := *f
+	if f.DeletedAt != nil {
+		deletedAt := *f.DeletedAt
+		c.DeletedAt = &deletedAt
+	}
+	return &c
+}
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/db/migrations"
+	"github.com/uptrace/bun/migrate"
+)
+
+// migrationLockTimeout is how long to wait for the other replica holding the
+// migration lock when the context has no deadline
+const migrationLockTimeout = 2 * time.Minute
+
+// Migrator returns the bun migrator for the schema migrations of the database dialect
+func (c *Config) Migrator() (*migrate.Migrator, error) {
+	return c.migrator("")
+}
+
+// migrator returns the bun migrator for the schema migrations up to the migration
+// named last, all of them when last is empty
+func (c *Config) migrator(last string) (*migrate.Migrator, error) {
+	ms, err := migrations.For(c.DB.Dialect().Name())
+	if err != nil {
+		return nil, err
+	}
+	if last != "" {
+		upTo := migrate.NewMigrations()
+		for _, m := range ms.Sorted() {
+			if m.Name <= last {
+				upTo.Add(m)
+			}
+		}
+		ms = upTo
+	}
+	return migrate.NewMigrator(c.DB, ms, migrate.WithMarkAppliedOnSuccess(true)), nil
+}
+
+// Migrate applies all the pending schema migrations, the migrations are
+// run holding the migration lock so that concurrent replicas don't race
+func (c *Config) Migrate(ctx context.Context) (*migrate.MigrationGroup, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.migrate(ctx, "")
+}
+
+// migrate applies the pending schema migrations up to the migration named last,
+// all of them when last is empty
+func (c *Config) migrate(ctx context.Context, last string) (*migrate.MigrationGroup, error) {
+	var group *migrate.MigrationGroup
+	err := c.withMigrationLock(ctx, last, func(m *migrate.Migrator) (err error) {
+		group, err = m.Migrate(ctx)
+		return err
+	})
+	if err != nil {
+		return group, fmt.Errorf("error applying migrations, %w", err)
+	}
+	if !group.IsZero() {
+		c.Log.Infof("Applied migrations %s", group)
+	}
+	return group, nil
+}
+
+// Rollback rolls back the last applied group of schema migrations
+func (c *Config) Rollback(ctx context.Context) (*migrate.MigrationGroup, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var group *migrate.MigrationGroup
+	err := c.withMigrationLock(ctx, "", func(m *migrate.Migrator) (err error) {
+		group, err = m.Rollback(ctx)
+		return err
+	})
+	if err != nil {
+		return group, fmt.Errorf("error rolling back migrations, %w", err)
+	}
+	return group, nil
+}
+
+// MigrationStatus returns all the schema migrations along with when they were applied
+func (c *Config) MigrationStatus(ctx context.Context) (migrate.MigrationSlice, error) {
+	m, err := c.Migrator()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Init(ctx); err != nil {
+		return nil, err
+	}
+	return m.MigrationsWithStatus(ctx)
+}
+
+// ForceUnlockMigrations releases the migration lock left behind by a replica
+// that crashed while migrating
+func (c *Config) ForceUnlockMigrations(ctx context.Context) error {
+	m, err := c.Migrator()
+	if err != nil {
+		return err
+	}
+	return m.Unlock(ctx)
+}
+
+// withMigrationLock runs fn with the migrator of the migrations up to last
+// holding the migration lock, it waits for the lock to be released when another
+// replica is holding it
+func (c *Config) withMigrationLock(ctx context.Context, last string, fn func(m *migrate.Migrator) error) error {
+	m, err := c.migrator(last)
+	if err != nil {
+		return err
+	}
+	if err := m.Init(ctx); err != nil {
+		return err
+	}
+	lockCtx := ctx
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		lockCtx, cancel = context.WithTimeout(ctx, migrationLockTimeout)
+		defer cancel()
+	}
+	for {
+		err := m.Lock(lockCtx)
+		if err == nil {
+			break
+		}
+		c.Log.Infof("Waiting for the migration lock, %v", err)
+		select {
+		case <-lockCtx.Done():
+			return fmt.Errorf("timed out waiting for the migration lock, run 'migrate unlock' if no other replica is migrating, %w", err)
+		case <-time.After(time.Second):
+		}
+	}
+	defer func() {
+		//the lock must be released even when the ctx is done
+		if err := m.Unlock(context.Background()); err != nil {
+			c.Log.Errorf("Error releasing the migration lock, %v", err)
+		}
+	}()
+	return fn(m)
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	log := utils.LogSetup(os.Stdout, utils.LookupEnvOrString("TEST_LOG_LEVEL", "info"))
+	dbc := New(
+		WithLogger(log),
+		WithDBType("sqlite"),
+		WithDBFile(path.Join(t.TempDir(), "migrate.db")),
+		WithAutoMigrate(false))
+	if err := dbc.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	ms, err := dbc.MigrationStatus(ctx)
+	if assert.NoError(t, err) && assert.NotEmpty(t, ms) {
+		assert.Empty(t, ms.Applied(), "Expecting no migrations to be applied")
+	}
+
+	group, err := dbc.Migrate(ctx)
+	if assert.NoError(t, err) {
+		assert.Equal(t, len(ms), len(group.Migrations))
+	}
+	exists, err := dbc.DB.NewSelect().Model((*Fruit)(nil)).Exists(ctx)
+	assert.NoError(t, err, "Expecting fruits table to be created")
+	assert.False(t, exists)
+
+	//re-running is a no-op
+	group, err = dbc.Migrate(ctx)
+	if assert.NoError(t, err) {
+		assert.True(t, group.IsZero(), "Expecting no migrations to be applied")
+	}
+
+	//the lock held by another replica makes migrate wait
+	m, err := dbc.Migrator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, m.Lock(ctx))
+	lockCtx, lockCancel := context.WithTimeout(ctx, 1500*time.Millisecond)
+	_, err = dbc.Migrate(lockCtx)
+	lockCancel()
+	assert.ErrorContains(t, err, "timed out waiting for the migration lock")
+	assert.NoError(t, dbc.ForceUnlockMigrations(ctx))
+
+	group, err = dbc.Rollback(ctx)
+	if assert.NoError(t, err) {
+		assert.Equal(t, len(ms), len(group.Migrations))
+	}
+	_, err = dbc.DB.NewSelect().Model((*Fruit)(nil)).Exists(ctx)
+	assert.Error(t, err, "Expecting fruits table to be dropped")
+}
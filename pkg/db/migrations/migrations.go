@@ -0,0 +1,43 @@
+// Package migrations holds the ordered schema migrations of the Fruits API.
+//
+// Each supported dialect has its own directory of SQL migrations named
+// <timestamp>_<name>.up.sql and <timestamp>_<name>.down.sql, every change to
+// the schema must be added as a new migration to all the dialect directories
+// with the same timestamp and name.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+
+	"github.com/uptrace/bun/dialect"
+	"github.com/uptrace/bun/migrate"
+)
+
+//go:embed sqlite pg mysql
+var files embed.FS
+
+// For returns the migrations of the dialect d
+func For(d dialect.Name) (*migrate.Migrations, error) {
+	var dir string
+	switch d {
+	case dialect.SQLite:
+		dir = "sqlite"
+	case dialect.PG:
+		dir = "pg"
+	case dialect.MySQL:
+		dir = "mysql"
+	default:
+		return nil, fmt.Errorf("no migrations for dialect %s", d)
+	}
+	fsys, err := fs.Sub(files, dir)
+	if err != nil {
+		return nil, err
+	}
+	m := migrate.NewMigrations()
+	if err := m.Discover(fsys); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS `fruits` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `name` VARCHAR(255) NOT NULL,
+  `season` VARCHAR(255) NOT NULL,
+  `emoji` VARCHAR(255),
+  `created_at` DATETIME NOT NULL DEFAULT current_timestamp,
+  `modified_at` DATETIME,
+  PRIMARY KEY (`id`)
+)
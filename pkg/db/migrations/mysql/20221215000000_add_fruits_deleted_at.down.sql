@@ -0,0 +1,5 @@
+DROP INDEX `fruits_deleted_at_idx` ON `fruits`
+
+--bun:split
+
+ALTER TABLE `fruits` DROP COLUMN `deleted_at`
@@ -0,0 +1,5 @@
+ALTER TABLE `fruits` ADD COLUMN `deleted_at` DATETIME
+
+--bun:split
+
+CREATE INDEX `fruits_deleted_at_idx` ON `fruits` (`deleted_at`)
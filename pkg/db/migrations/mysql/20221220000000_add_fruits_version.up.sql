@@ -0,0 +1 @@
+ALTER TABLE `fruits` ADD COLUMN `version` BIGINT NOT NULL DEFAULT 1
@@ -0,0 +1,5 @@
+DROP INDEX `fruits_name_uidx` ON `fruits`
+
+--bun:split
+
+ALTER TABLE `fruits` DROP COLUMN `name_key`
@@ -0,0 +1,5 @@
+ALTER TABLE `fruits` ADD COLUMN `name_key` VARCHAR(255) AS (CASE WHEN `deleted_at` IS NULL THEN UPPER(`name`) END) STORED
+
+--bun:split
+
+CREATE UNIQUE INDEX `fruits_name_uidx` ON `fruits` (`name_key`)
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS `fruit_revisions` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `fruit_id` BIGINT NOT NULL,
+  `version` INT NOT NULL,
+  `operation` VARCHAR(255) NOT NULL,
+  `actor` VARCHAR(255) NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT current_timestamp,
+  `before` JSON,
+  `after` JSON,
+  PRIMARY KEY (`id`)
+)
+
+--bun:split
+
+CREATE INDEX `fruit_revisions_fruit_id_idx` ON `fruit_revisions` (`fruit_id`)
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS `seeds` (
+  `name` VARCHAR(255) NOT NULL,
+  `hash` VARCHAR(64) NOT NULL,
+  `applied_at` DATETIME NOT NULL DEFAULT current_timestamp,
+  PRIMARY KEY (`name`)
+)
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS "fruits" (
+  "id" BIGSERIAL NOT NULL,
+  "name" VARCHAR NOT NULL,
+  "season" VARCHAR NOT NULL,
+  "emoji" VARCHAR,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+  "modified_at" TIMESTAMPTZ,
+  PRIMARY KEY ("id")
+)
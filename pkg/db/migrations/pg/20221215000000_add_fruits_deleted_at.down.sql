@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "fruits_deleted_at_idx"
+
+--bun:split
+
+ALTER TABLE "fruits" DROP COLUMN IF EXISTS "deleted_at"
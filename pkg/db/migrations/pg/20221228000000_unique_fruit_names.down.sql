@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "fruits_name_uidx"
@@ -0,0 +1 @@
+CREATE UNIQUE INDEX "fruits_name_uidx" ON "fruits" (UPPER("name")) WHERE "deleted_at" IS NULL
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS "fruit_revisions" (
+  "id" BIGSERIAL NOT NULL,
+  "fruit_id" BIGINT NOT NULL,
+  "version" INTEGER NOT NULL,
+  "operation" VARCHAR NOT NULL,
+  "actor" VARCHAR NOT NULL,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+  "before" JSONB,
+  "after" JSONB,
+  PRIMARY KEY ("id")
+)
+
+--bun:split
+
+CREATE INDEX "fruit_revisions_fruit_id_idx" ON "fruit_revisions" ("fruit_id")
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS "seeds" (
+  "name" VARCHAR NOT NULL,
+  "hash" VARCHAR NOT NULL,
+  "applied_at" TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+  PRIMARY KEY ("name")
+)
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS "fruits" (
+  "id" INTEGER NOT NULL,
+  "name" VARCHAR NOT NULL,
+  "season" VARCHAR NOT NULL,
+  "emoji" VARCHAR,
+  "created_at" TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  "modified_at" TIMESTAMP,
+  PRIMARY KEY ("id")
+)
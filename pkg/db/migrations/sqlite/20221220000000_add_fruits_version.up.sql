@@ -0,0 +1 @@
+ALTER TABLE "fruits" ADD COLUMN "version" INTEGER NOT NULL DEFAULT 1
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS "fruit_revisions" (
+  "id" INTEGER NOT NULL,
+  "fruit_id" INTEGER NOT NULL,
+  "version" INTEGER NOT NULL,
+  "operation" VARCHAR NOT NULL,
+  "actor" VARCHAR NOT NULL,
+  "created_at" TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  "before" TEXT,
+  "after" TEXT,
+  PRIMARY KEY ("id")
+)
+
+--bun:split
+
+CREATE INDEX "fruit_revisions_fruit_id_idx" ON "fruit_revisions" ("fruit_id")
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PurgeTrash permanently deletes the fruits that have been in the trash for
+// longer than the retention, it purges on start and then every interval
+// until the ctx is done
+func PurgeTrash(ctx context.Context, log *logrus.Logger, repo FruitRepository, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := repo.Purge(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Errorf("Error purging the deleted fruits, %v", err)
+		} else if n > 0 {
+			log.Infof("Purged %d fruits deleted more than %s ago", n, retention)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
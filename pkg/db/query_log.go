@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/uptrace/bun"
+)
+
+// queryLogger is the bun query hook logging the queries tagged with the id of
+// the request they were made for. The queries are logged at the debug level,
+// the failed ones are always logged as errors.
+type queryLogger struct {
+	log *logrus.Logger
+}
+
+var _ bun.QueryHook = (*queryLogger)(nil)
+
+// BeforeQuery implements bun.QueryHook
+func (h *queryLogger) BeforeQuery(ctx context.Context, event *bun.QueryEvent) context.Context {
+	return ctx
+}
+
+// AfterQuery implements bun.QueryHook
+func (h *queryLogger) AfterQuery(ctx context.Context, event *bun.QueryEvent) {
+	failed := false
+	switch event.Err {
+	case nil, sql.ErrNoRows, sql.ErrTxDone:
+	default:
+		failed = true
+	}
+	if !failed && !h.log.IsLevelEnabled(logrus.DebugLevel) {
+		return
+	}
+	entry := utils.LogEntry(ctx, h.log).WithFields(logrus.Fields{
+		"operation":   event.Operation(),
+		"duration_ms": float64(time.Since(event.StartTime)) / float64(time.Millisecond),
+		"query":       event.Query,
+	})
+	if failed {
+		entry.WithError(event.Err).Error("query failed")
+		return
+	}
+	entry.Debug("query")
+}
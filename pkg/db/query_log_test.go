@@ -0,0 +1,62 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryLogger(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var out bytes.Buffer
+	log := utils.LogSetup(&out, "info")
+	dbc := New(
+		WithLogger(log),
+		WithDBType("sqlite"),
+		WithDBFile(path.Join(t.TempDir(), "query_log.db")))
+	if err := dbc.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewBunFruitRepository(dbc)
+	ctx = utils.WithRequestID(ctx, "f00d")
+
+	out.Reset()
+	_, _, err := repo.List(ctx, ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, out.String(), "Expecting the queries to be logged at the debug level")
+
+	_, err = dbc.DB.NewSelect().Table("vegetables").Exists(ctx)
+	assert.Error(t, err)
+	got := lastLogLine(t, &out)
+	assert.Equal(t, "error", got["level"])
+	assert.Equal(t, "f00d", got[utils.FieldRequestID])
+	assert.Equal(t, "SELECT", got["operation"])
+	assert.Contains(t, got["query"], "vegetables")
+	assert.Contains(t, got, "error")
+
+	log.SetLevel(logrus.DebugLevel)
+	_, err = repo.Get(ctx, 1)
+	assert.Error(t, err)
+	got = lastLogLine(t, &out)
+	assert.Equal(t, "debug", got["level"], "Expecting no rows not to be logged as an error")
+	assert.Equal(t, "f00d", got[utils.FieldRequestID])
+	assert.Contains(t, got, "duration_ms")
+}
+
+func lastLogLine(t *testing.T, out *bytes.Buffer) map[string]interface{} {
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &got); err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/query"
+)
+
+// ListOptions restricts and orders the fruits returned by FruitRepository List
+type ListOptions struct {
+	// Filter matches the fruits to list, nil matches all the fruits
+	Filter query.Expr
+	// Sort is the order of the fruits, it must end with a unique field
+	// like the id to make the order stable
+	Sort query.Sort
+	// After are the Sort values of the last fruit of the previous page,
+	// only the fruits sorted after it are listed
+	After []interface{}
+	// Limit is the maximum number of fruits to list, 0 lists all of them
+	Limit  int
+	Offset int
+}
+
+// Criteria are the attributes to search the fruits by, the empty
+// attributes are ignored
+type Criteria struct {
+	// Name matches the fruits having the full or partial name, ignoring the case
+	Name string
+	// Season matches the fruits of the season, ignoring the case
+	Season string
+}
+
+// FruitRepository stores the fruits. The methods return
+// apperrors.NotFoundError when the fruit does not exist. Each change of
+// a fruit is recorded as a FruitRevision made by the Actor of the context.
+type FruitRepository interface {
+	// Create adds the fruit, the fruit ID is set when it has none
+	Create(ctx context.Context, f *Fruit) error
+	// Get gets the fruit with the id
+	Get(ctx context.Context, id int) (*Fruit, error)
+	// List lists the page of fruits along with the total number of fruits
+	// matching the opts Filter
+	List(ctx context.Context, opts ListOptions) (Fruits, int, error)
+	// Search lists the page of the fruits matching the criteria along with
+	// the total number of matching fruits
+	Search(ctx context.Context, criteria Criteria, opts ListOptions) (Fruits, int, error)
+	// Update saves the name, season and emoji of the fruit and increments
+	// its Version. When the fruit Version is not 0 the fruit is only saved
+	// if it still has that version, otherwise apperrors.PreconditionFailedError
+	// is returned.
+	Update(ctx context.Context, f *Fruit) error
+	// Delete moves the fruit with the id to the trash, deleting a fruit
+	// that does not exist is not an error. When the version is not 0 the
+	// fruit is only deleted if it still has that version, otherwise
+	// apperrors.PreconditionFailedError is returned.
+	Delete(ctx context.Context, id int, version int) error
+	// DeleteAll moves all the fruits to the trash
+	DeleteAll(ctx context.Context) error
+	// ListDeleted lists the page of fruits in the trash along with the
+	// total number of deleted fruits matching the opts Filter
+	ListDeleted(ctx context.Context, opts ListOptions) (Fruits, int, error)
+	// Restore brings back the fruit with the id from the trash
+	Restore(ctx context.Context, id int) (*Fruit, error)
+	// Purge permanently deletes the fruits that were moved to the trash
+	// before the time, it returns the number of purged fruits
+	Purge(ctx context.Context, before time.Time) (int, error)
+	// Batch applies the operations and returns the result of each of them.
+	// When atomic is true either all the operations are applied or none of
+	// them is, the operations that were not applied fail with ErrBatchRolledBack.
+	// Otherwise each operation is applied on its own.
+	Batch(ctx context.Context, ops []BatchOperation, atomic bool) ([]BatchResult, error)
+	// Import adds the fruits read from rd, the rows having the names of
+	// existing fruits update them or are skipped as told by opts. The invalid
+	// rows are rejected, none of the rows is applied when rd fails or with a
+	// dry-run.
+	Import(ctx context.Context, rd ImportReader, opts ImportOptions) (*ImportResult, error)
+	// Export calls fn with each fruit that is not in the trash, ordered by
+	// the id. The fruits are read one at a time, the error of fn stops the export.
+	Export(ctx context.Context, fn func(f *Fruit) error) error
+	// CountBySeason counts the fruits of each season, the fruits in the trash
+	// are not counted
+	CountBySeason(ctx context.Context) (map[string]int, error)
+	// History lists the revisions of the fruit with the id, oldest first. The
+	// history of the purged fruits is kept.
+	History(ctx context.Context, id int) (FruitRevisions, error)
+	// Revert sets the name, season and emoji of the fruit with the id back to
+	// what they were after the revision. The version is handled as by Update.
+	Revert(ctx context.Context, id int, revision int, version int) (*Fruit, error)
+	// Ping checks the repository is available
+	Ping(ctx context.Context) error
+}
+
+// fruitFields are the queryable fields of Fruit
+var fruitFields = query.FieldsOf((*Fruit)(nil))
+
+// FruitFields returns the Fruit fields that could be used to filter and sort
+func FruitFields() query.Fields {
+	return fruitFields
+}
+
+// Filter returns the filter expression of the criteria combined with
+// the filter, the result is nil when there is nothing to filter on
+func (cr Criteria) Filter(filter query.Expr) query.Expr {
+	if cr.Name != "" {
+		filter = and(filter, &query.Comparison{Field: fruitFields["name"], Op: query.Like, Value: cr.Name})
+	}
+	if cr.Season != "" {
+		filter = and(filter, &query.Comparison{Field: fruitFields["season"], Op: query.Eq, Value: cr.Season})
+	}
+	return filter
+}
+
+func and(left, right query.Expr) query.Expr {
+	if left == nil {
+		return right
+	}
+	return &query.And{Left: left, Right: right}
+}
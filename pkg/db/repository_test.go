@@ -0,0 +1,552 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+	"github.com/kameshsampath/go-fruits-api/pkg/query"
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// testRepositories are the FruitRepository implementations to test, each
+// function creates an empty repository
+func testRepositories(ctx context.Context) map[string]func(t *testing.T) FruitRepository {
+	log := utils.LogSetup(os.Stdout, utils.LookupEnvOrString("TEST_LOG_LEVEL", "info"))
+	return map[string]func(t *testing.T) FruitRepository{
+		"memory": func(t *testing.T) FruitRepository {
+			return NewMemoryFruitRepository()
+		},
+		"bun": func(t *testing.T) FruitRepository {
+			dbc := New(
+				WithLogger(log),
+				WithDBType("sqlite"),
+				WithDBFile(path.Join(t.TempDir(), "repository.db")))
+			if err := dbc.Init(ctx); err != nil {
+				t.Fatal(err)
+			}
+			return NewBunFruitRepository(dbc)
+		},
+	}
+}
+
+func TestFruitRepository(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	repos := testRepositories(ctx)
+
+	sortByName, err := query.ParseSort("name,id", FruitFields())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for name, newRepo := range repos {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo(t)
+			for _, f := range []*Fruit{
+				{Name: "Mango", Season: "Spring"},
+				{Name: "Strawberry", Season: "Spring"},
+				{Name: "Blueberry", Season: "Summer"},
+				{Name: "Banana", Season: "Summer"},
+			} {
+				if !assert.NoError(t, repo.Create(ctx, f)) {
+					return
+				}
+				assert.NotZero(t, f.ID)
+			}
+
+			fruits, total, err := repo.List(ctx, ListOptions{Sort: sortByName, Limit: 2})
+			if assert.NoError(t, err) {
+				assert.Equal(t, 4, total)
+				assert.Equal(t, []string{"Banana", "Blueberry"}, names(fruits))
+			}
+			fruits, _, err = repo.List(ctx, ListOptions{Sort: sortByName, Limit: 2, After: sortByName.Values(fruits[1])})
+			if assert.NoError(t, err) {
+				assert.Equal(t, []string{"Mango", "Strawberry"}, names(fruits))
+			}
+
+			fruits, total, err = repo.Search(ctx, Criteria{Name: "BERRY"}, ListOptions{Sort: sortByName})
+			if assert.NoError(t, err) {
+				assert.Equal(t, 2, total)
+				assert.Equal(t, []string{"Blueberry", "Strawberry"}, names(fruits))
+			}
+			err = repo.Create(ctx, &Fruit{Name: "mango", Season: "Summer"})
+			var conflict *apperrors.ConflictError
+			if assert.True(t, errors.As(err, &conflict), "Expecting conflict but got %v", err) {
+				assert.Equal(t, 1, conflict.ExistingID)
+			}
+
+			fruits, _, err = repo.Search(ctx, Criteria{Season: "summer"}, ListOptions{Sort: sortByName, Offset: 1, Limit: 10})
+			if assert.NoError(t, err) {
+				assert.Equal(t, []string{"Blueberry"}, names(fruits))
+			}
+
+			f, err := repo.Get(ctx, fruits[0].ID)
+			if assert.NoError(t, err) {
+				assert.Equal(t, 1, f.Version)
+				f.Season = "Fall"
+				assert.NoError(t, repo.Update(ctx, f))
+				assert.Equal(t, 2, f.Version)
+				got, err := repo.Get(ctx, f.ID)
+				if assert.NoError(t, err) {
+					assert.Equal(t, "Fall", got.Season)
+					assert.Equal(t, 2, got.Version)
+				}
+				stale := *f
+				stale.Version = 1
+				err = repo.Update(ctx, &stale)
+				assert.True(t, errors.As(err, new(*apperrors.PreconditionFailedError)), "Expecting precondition failed but got %v", err)
+				assert.Equal(t, 1, stale.Version, "Expecting the version of a failed update to be kept")
+				err = repo.Delete(ctx, f.ID, 1)
+				assert.True(t, errors.As(err, new(*apperrors.PreconditionFailedError)), "Expecting precondition failed but got %v", err)
+				assert.NoError(t, repo.Delete(ctx, f.ID, 2))
+				_, err = repo.Get(ctx, f.ID)
+				assert.True(t, errors.As(err, new(*apperrors.NotFoundError)), "Expecting not found but got %v", err)
+				err = repo.Update(ctx, f)
+				assert.True(t, errors.As(err, new(*apperrors.NotFoundError)), "Expecting not found but got %v", err)
+			}
+
+			fruits, total, err = repo.ListDeleted(ctx, ListOptions{Sort: sortByName})
+			if assert.NoError(t, err) && assert.Equal(t, 1, total) {
+				assert.Equal(t, "Blueberry", fruits[0].Name)
+				assert.NotNil(t, fruits[0].DeletedAt)
+			}
+			blueberry := &Fruit{Name: "BLUEBERRY", Season: "Summer"}
+			if assert.NoError(t, repo.Create(ctx, blueberry), "Expecting the name of a deleted fruit to be available") {
+				_, err = repo.Restore(ctx, fruits[0].ID)
+				assert.True(t, errors.As(err, new(*apperrors.ConflictError)), "Expecting conflict but got %v", err)
+				blueberry.Name = "Bilberry"
+				assert.NoError(t, repo.Update(ctx, blueberry))
+			}
+			restored, err := repo.Restore(ctx, fruits[0].ID)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "Blueberry", restored.Name)
+				assert.Nil(t, restored.DeletedAt)
+				assert.Equal(t, 3, restored.Version)
+			}
+			_, err = repo.Restore(ctx, fruits[0].ID)
+			assert.True(t, errors.As(err, new(*apperrors.NotFoundError)), "Expecting not found but got %v", err)
+
+			assert.NoError(t, repo.DeleteAll(ctx))
+			_, total, err = repo.List(ctx, ListOptions{Sort: sortByName})
+			if assert.NoError(t, err) {
+				assert.Zero(t, total)
+			}
+			_, total, err = repo.ListDeleted(ctx, ListOptions{Sort: sortByName})
+			if assert.NoError(t, err) {
+				assert.Equal(t, 5, total, "Expecting all the fruits to be in the trash")
+			}
+			n, err := repo.Purge(ctx, time.Now().Add(-time.Hour))
+			if assert.NoError(t, err) {
+				assert.Zero(t, n, "Expecting the recently deleted fruits to be kept")
+			}
+			n, err = repo.Purge(ctx, time.Now().Add(time.Second))
+			if assert.NoError(t, err) {
+				assert.Equal(t, 5, n)
+			}
+			_, total, err = repo.ListDeleted(ctx, ListOptions{Sort: sortByName})
+			if assert.NoError(t, err) {
+				assert.Zero(t, total)
+			}
+			assert.NoError(t, repo.Ping(ctx))
+		})
+	}
+}
+
+func TestFruitRepositorySeasonCase(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for name, newRepo := range testRepositories(ctx) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo(t)
+			f := &Fruit{Name: "Mango", Season: "summer"}
+			if !assert.NoError(t, repo.Create(ctx, f)) {
+				return
+			}
+			got, err := repo.Get(ctx, f.ID)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "Summer", got.Season, "Expecting the season to be saved the way it is listed")
+			}
+			f.Season = "SPRING"
+			if !assert.NoError(t, repo.Update(ctx, f)) {
+				return
+			}
+			got, err = repo.Get(ctx, f.ID)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "Spring", got.Season)
+			}
+		})
+	}
+}
+
+func TestFruitRepositoryBatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	sortByName, err := query.ParseSort("name,id", FruitFields())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for name, newRepo := range testRepositories(ctx) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo(t)
+			for _, f := range []*Fruit{
+				{Name: "Mango", Season: "Spring"},
+				{Name: "Strawberry", Season: "Spring"},
+			} {
+				if !assert.NoError(t, repo.Create(ctx, f)) {
+					return
+				}
+			}
+			list := func() []string {
+				fruits, _, err := repo.List(ctx, ListOptions{Sort: sortByName})
+				assert.NoError(t, err)
+				var got []string
+				for _, f := range fruits {
+					got = append(got, f.Name+" "+f.Season)
+				}
+				return got
+			}
+
+			results, err := repo.Batch(ctx, []BatchOperation{
+				{Op: BatchInsert, Fruit: &Fruit{Name: "Kiwi", Season: "Winter"}},
+				{Op: BatchUpsert, Fruit: &Fruit{Name: "MANGO", Season: "Summer"}},
+				{Op: BatchUpsert, Fruit: &Fruit{Name: "Papaya", Season: "Summer"}},
+				{Op: BatchDelete, ID: 2},
+			}, true)
+			if assert.NoError(t, err) && assert.Len(t, results, 4) {
+				for i, r := range results {
+					assert.NoError(t, r.Err, "Expecting operation %d to succeed", i)
+				}
+				assert.True(t, results[0].Created)
+				assert.Equal(t, 1, results[1].Fruit.ID, "Expecting the upsert to update Mango")
+				assert.False(t, results[1].Created)
+				assert.Equal(t, 2, results[1].Fruit.Version)
+				assert.True(t, results[2].Created)
+				assert.Nil(t, results[3].Fruit)
+			}
+			assert.Equal(t, []string{"Kiwi Winter", "MANGO Summer", "Papaya Summer"}, list())
+
+			failing := func() []BatchOperation {
+				return []BatchOperation{
+					{Op: BatchInsert, Fruit: &Fruit{Name: "Fig", Season: "Fall"}},
+					{Op: BatchInsert, Fruit: &Fruit{ID: 1, Name: "Mango", Season: "Spring"}},
+					{Op: "replace", Fruit: &Fruit{Name: "Pear", Season: "Fall"}},
+					{Op: BatchDelete, ID: 1},
+				}
+			}
+			results, err = repo.Batch(ctx, failing(), true)
+			if assert.NoError(t, err) && assert.Len(t, results, 4) {
+				assert.ErrorIs(t, results[0].Err, ErrBatchRolledBack)
+				assert.Error(t, results[1].Err)
+				assert.NotErrorIs(t, results[1].Err, ErrBatchRolledBack)
+				assert.ErrorIs(t, results[2].Err, ErrBatchRolledBack, "Expecting the operations after the failure not to be applied")
+				assert.ErrorIs(t, results[3].Err, ErrBatchRolledBack)
+			}
+			assert.Equal(t, []string{"Kiwi Winter", "MANGO Summer", "Papaya Summer"}, list())
+
+			results, err = repo.Batch(ctx, failing(), false)
+			if assert.NoError(t, err) && assert.Len(t, results, 4) {
+				assert.NoError(t, results[0].Err)
+				assert.Error(t, results[1].Err)
+				assert.Nil(t, results[1].Fruit)
+				assert.True(t, errors.As(results[2].Err, new(*apperrors.BadRequestError)), "Expecting bad request but got %v", results[2].Err)
+				assert.NoError(t, results[3].Err)
+			}
+			assert.Equal(t, []string{"Fig Fall", "Kiwi Winter", "Papaya Summer"}, list())
+		})
+	}
+}
+
+func TestFruitRepositoryHistory(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for name, newRepo := range testRepositories(ctx) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo(t)
+			alice, bob := WithActor(ctx, "alice"), WithActor(ctx, "bob")
+			mango := &Fruit{Name: "Mango", Season: "Spring"}
+			if !assert.NoError(t, repo.Create(alice, mango)) {
+				return
+			}
+			assert.NoError(t, repo.Create(ctx, &Fruit{Name: "Apple", Season: "Fall"}))
+			mango.Season, mango.Emoji = "Summer", "U+1F96D"
+			assert.NoError(t, repo.Update(alice, mango))
+			assert.NoError(t, repo.Delete(bob, mango.ID, 0))
+			_, err := repo.Restore(bob, mango.ID)
+			assert.NoError(t, err)
+
+			revisions, err := repo.History(ctx, mango.ID)
+			if !assert.NoError(t, err) || !assert.Len(t, revisions, 4) {
+				return
+			}
+			var ops []RevisionOp
+			var actors []string
+			for _, rev := range revisions {
+				assert.Equal(t, mango.ID, rev.FruitID)
+				assert.False(t, rev.CreatedAt.IsZero())
+				ops = append(ops, rev.Operation)
+				actors = append(actors, rev.Actor)
+			}
+			assert.Equal(t, []RevisionOp{RevisionInsert, RevisionUpdate, RevisionDelete, RevisionRestore}, ops)
+			assert.Equal(t, []string{"alice", "alice", "bob", "bob"}, actors)
+			assert.Nil(t, revisions[0].Before)
+			assert.JSONEq(t, `{"id":1,"name":"Mango","season":"Spring"}`, string(revisions[0].After))
+			assert.JSONEq(t, `{"id":1,"name":"Mango","season":"Spring"}`, string(revisions[1].Before))
+			assert.JSONEq(t, `{"id":1,"name":"Mango","season":"Summer","emoji":"U+1F96D"}`, string(revisions[1].After))
+			assert.Nil(t, revisions[2].After)
+			assert.Equal(t, []int{1, 2, 2, 3}, []int{revisions[0].Version, revisions[1].Version, revisions[2].Version, revisions[3].Version})
+
+			_, err = repo.Revert(ctx, mango.ID, revisions[2].ID, 0)
+			assert.True(t, errors.As(err, new(*apperrors.BadRequestError)), "Expecting bad request but got %v", err)
+			apple, err := repo.History(ctx, 2)
+			if assert.NoError(t, err) && assert.Len(t, apple, 1) {
+				_, err = repo.Revert(ctx, mango.ID, apple[0].ID, 0)
+				assert.True(t, errors.As(err, new(*apperrors.NotFoundError)), "Expecting not found but got %v", err)
+				assert.Equal(t, SystemActor, apple[0].Actor)
+			}
+			_, err = repo.Revert(ctx, mango.ID, revisions[0].ID, 2)
+			assert.True(t, errors.As(err, new(*apperrors.PreconditionFailedError)), "Expecting precondition failed but got %v", err)
+			reverted, err := repo.Revert(alice, mango.ID, revisions[0].ID, 3)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "Spring", reverted.Season)
+				assert.Empty(t, reverted.Emoji)
+				assert.Equal(t, 4, reverted.Version)
+			}
+			got, err := repo.Get(ctx, mango.ID)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "Spring", got.Season)
+				assert.Empty(t, got.Emoji)
+			}
+
+			//the history outlives the purged fruits
+			assert.NoError(t, repo.Delete(ctx, mango.ID, 0))
+			_, err = repo.Purge(ctx, time.Now().Add(time.Second))
+			assert.NoError(t, err)
+			revisions, err = repo.History(ctx, mango.ID)
+			if assert.NoError(t, err) && assert.Len(t, revisions, 7) {
+				assert.Equal(t, RevisionRevert, revisions[4].Operation)
+				assert.Equal(t, RevisionPurge, revisions[6].Operation)
+				assert.NotNil(t, revisions[6].Before)
+			}
+
+			_, err = repo.History(ctx, 42)
+			assert.True(t, errors.As(err, new(*apperrors.NotFoundError)), "Expecting not found but got %v", err)
+		})
+	}
+}
+
+// rowsReader is the ImportReader of the rows, failing with err after them
+type rowsReader struct {
+	rows []*ImportRow
+	err  error
+}
+
+func (r *rowsReader) Next() (*ImportRow, error) {
+	if len(r.rows) == 0 {
+		if r.err != nil {
+			return nil, r.err
+		}
+		return nil, io.EOF
+	}
+	row := r.rows[0]
+	r.rows = r.rows[1:]
+	return row, nil
+}
+
+func TestFruitRepositoryImport(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	sortByName, err := query.ParseSort("name,id", FruitFields())
+	if !assert.NoError(t, err) {
+		return
+	}
+	rows := func() []*ImportRow {
+		return []*ImportRow{
+			{Line: 2, Fruit: &Fruit{Name: "Kiwi", Season: "Winter"}},
+			{Line: 3, Fruit: &Fruit{Name: "MANGO", Season: "Summer"}},
+			{Line: 4, Fruit: &Fruit{Name: "Strawberry", Season: "Spring"}},
+			{Line: 5, Err: errors.New("wrong number of fields")},
+			{Line: 6, Fruit: &Fruit{Name: "Fig"}},
+			{Line: 7, Fruit: &Fruit{Name: "kiwi", Season: "Fall"}},
+		}
+	}
+
+	for name, newRepo := range testRepositories(ctx) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo(t)
+			for _, f := range []*Fruit{
+				{Name: "Mango", Season: "Spring"},
+				{Name: "Strawberry", Season: "Spring"},
+			} {
+				if !assert.NoError(t, repo.Create(ctx, f)) {
+					return
+				}
+			}
+			list := func() []string {
+				fruits, _, err := repo.List(ctx, ListOptions{Sort: sortByName})
+				assert.NoError(t, err)
+				var got []string
+				for _, f := range fruits {
+					got = append(got, f.Name+" "+f.Season)
+				}
+				return got
+			}
+			opts := ImportOptions{Validator: seasonValidator{}}
+
+			opts.DryRun = true
+			res, err := repo.Import(ctx, &rowsReader{rows: rows()}, opts)
+			if assert.NoError(t, err) {
+				assert.Equal(t, &ImportResult{
+					DryRun:    true,
+					Inserted:  1,
+					Skipped:   2,
+					Unchanged: 1,
+					Rejected:  2,
+					Changes: []ImportChange{
+						{Line: 2, Action: ImportInsert, After: ImportValues{Name: "Kiwi", Season: "Winter"}},
+						{Line: 3, Action: ImportSkip, ID: 1, Before: &ImportValues{Name: "Mango", Season: "Spring"}, After: ImportValues{Name: "MANGO", Season: "Summer"}},
+						{Line: 7, Action: ImportSkip, ID: 3, Before: &ImportValues{Name: "Kiwi", Season: "Winter"}, After: ImportValues{Name: "kiwi", Season: "Fall"}},
+					},
+					Rejections: []ImportRejection{
+						{Line: 5, Error: "wrong number of fields"},
+						{Line: 6, Error: "season is required"},
+					},
+				}, res)
+			}
+			assert.Equal(t, []string{"Mango Spring", "Strawberry Spring"}, list(), "Expecting a dry-run not to change the fruits")
+
+			opts.DryRun = false
+			_, err = repo.Import(ctx, &rowsReader{rows: rows(), err: errors.New("connection reset")}, opts)
+			assert.EqualError(t, err, "connection reset")
+			assert.Equal(t, []string{"Mango Spring", "Strawberry Spring"}, list(), "Expecting a failed import not to change the fruits")
+
+			opts.Update = true
+			res, err = repo.Import(WithActor(ctx, "import"), &rowsReader{rows: rows()}, opts)
+			if assert.NoError(t, err) {
+				assert.Equal(t, 1, res.Inserted)
+				assert.Equal(t, 2, res.Updated)
+				assert.Equal(t, 1, res.Unchanged)
+				assert.Equal(t, 2, res.Rejected)
+				assert.Equal(t, 3, res.Changes[0].ID, "Expecting the id of the inserted fruit")
+			}
+			assert.Equal(t, []string{"MANGO Summer", "Strawberry Spring", "kiwi Fall"}, list())
+			revisions, err := repo.History(ctx, 1)
+			if assert.NoError(t, err) && assert.Len(t, revisions, 2) {
+				assert.Equal(t, RevisionUpdate, revisions[1].Operation)
+				assert.Equal(t, "import", revisions[1].Actor)
+			}
+		})
+	}
+}
+
+func TestFruitRepositoryExport(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for name, newRepo := range testRepositories(ctx) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo(t)
+			for _, f := range []*Fruit{
+				{Name: "Mango", Season: "Spring"},
+				{Name: "Apple", Season: "Fall"},
+				{Name: "Kiwi", Season: "Winter"},
+				{Name: "Banana", Season: "Summer"},
+			} {
+				if !assert.NoError(t, repo.Create(ctx, f)) {
+					return
+				}
+			}
+			if !assert.NoError(t, repo.Delete(ctx, 3, 0)) {
+				return
+			}
+
+			var got Fruits
+			err := repo.Export(ctx, func(f *Fruit) error {
+				got = append(got, f)
+				return nil
+			})
+			if assert.NoError(t, err) {
+				assert.Equal(t, []string{"Mango", "Apple", "Banana"}, names(got), "Expecting the fruits not in the trash ordered by the id")
+			}
+
+			//an error of fn stops the export
+			n := 0
+			err = repo.Export(ctx, func(f *Fruit) error {
+				n++
+				return errors.New("broken pipe")
+			})
+			assert.EqualError(t, err, "broken pipe")
+			assert.Equal(t, 1, n)
+		})
+	}
+}
+
+func TestFruitRepositoryCountBySeason(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for name, newRepo := range testRepositories(ctx) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo(t)
+			for _, f := range []*Fruit{
+				{Name: "Mango", Season: "Spring"},
+				{Name: "Apple", Season: "Fall"},
+				{Name: "Pear", Season: "fall"},
+				{Name: "Kiwi", Season: "Winter"},
+			} {
+				if !assert.NoError(t, repo.Create(ctx, f)) {
+					return
+				}
+			}
+			if !assert.NoError(t, repo.Delete(ctx, 4, 0)) {
+				return
+			}
+			counts, err := repo.CountBySeason(ctx)
+			if assert.NoError(t, err) {
+				assert.Equal(t, map[string]int{"Spring": 1, "Fall": 2}, counts, "Expecting the fruits in the trash not to be counted")
+			}
+		})
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	log := utils.LogSetup(os.Stdout, utils.LookupEnvOrString("TEST_LOG_LEVEL", "info"))
+	repo := NewMemoryFruitRepository(&Fruit{Name: "Mango", Season: "Spring"}, &Fruit{Name: "Apple", Season: "Fall"})
+	if err := repo.Delete(ctx, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		PurgeTrash(ctx, log, repo, 10*time.Millisecond, 10*time.Millisecond)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool {
+		_, total, err := repo.ListDeleted(ctx, ListOptions{})
+		return err == nil && total == 0
+	}, time.Second, 10*time.Millisecond)
+	_, total, err := repo.List(ctx, ListOptions{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, total, "Expecting the fruits not in the trash to be kept")
+	}
+	cancel()
+	<-done
+}
+
+func names(fruits Fruits) []string {
+	var names []string
+	for _, f := range fruits {
+		names = append(names, f.Name)
+	}
+	return names
+}
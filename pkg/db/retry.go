@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Retry configures the exponential backoff of the attempts to reach the
+// database, like the database starting along with the application
+type Retry struct {
+	// Timeout is how long to keep retrying, zero makes a single attempt
+	Timeout time.Duration
+	// InitialInterval is the wait before the second attempt
+	InitialInterval time.Duration
+	// MaxInterval caps the wait between the attempts
+	MaxInterval time.Duration
+	// Multiplier grows the wait after each attempt
+	Multiplier float64
+	// Jitter is the fraction of the wait that is randomized, so that the
+	// replicas starting together don't retry in lockstep
+	Jitter float64
+}
+
+// DefaultRetry retries for a minute waiting from half a second up to ten
+// seconds between the attempts
+var DefaultRetry = Retry{
+	Timeout:         time.Minute,
+	InitialInterval: 500 * time.Millisecond,
+	MaxInterval:     10 * time.Second,
+	Multiplier:      2,
+	Jitter:          0.2,
+}
+
+// interval returns the wait after the attempt, counted from zero
+func (r Retry) interval(attempt int) time.Duration {
+	d := float64(r.InitialInterval) * math.Pow(r.Multiplier, float64(attempt))
+	if r.MaxInterval > 0 && d > float64(r.MaxInterval) {
+		d = float64(r.MaxInterval)
+	}
+	if r.Jitter > 0 {
+		//randomize within d ± Jitter*d
+		d += d * r.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+// Do calls fn until it succeeds, the Timeout elapses or ctx is done. The
+// failed attempts are logged as warnings, the returned error is the one of
+// the last attempt.
+func (r Retry) Do(ctx context.Context, log *logrus.Logger, what string, fn func(ctx context.Context) error) error {
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		wait := r.interval(attempt)
+		if r.Timeout <= 0 || time.Since(start)+wait > r.Timeout {
+			return fmt.Errorf("unable to %s after %d attempts, %w", what, attempt+1, err)
+		}
+		log.Warnf("Unable to %s, retrying in %s, %v", what, wait.Round(time.Millisecond), err)
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("unable to %s, %w", what, err)
+		case <-t.C:
+		}
+	}
+}
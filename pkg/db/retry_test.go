@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryInterval(t *testing.T) {
+	r := Retry{
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     time.Second,
+		Multiplier:      2,
+	}
+	var got []time.Duration
+	for attempt := 0; attempt < 6; attempt++ {
+		got = append(got, r.interval(attempt))
+	}
+	assert.Equal(t, []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}, got)
+
+	r.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := r.interval(1)
+		assert.GreaterOrEqual(t, d, 100*time.Millisecond)
+		assert.LessOrEqual(t, d, 300*time.Millisecond)
+	}
+}
+
+func TestRetryDo(t *testing.T) {
+	log := utils.LogSetup(io.Discard, "info")
+	errDown := errors.New("connection refused")
+	r := Retry{
+		Timeout:         time.Second,
+		InitialInterval: 10 * time.Millisecond,
+		MaxInterval:     50 * time.Millisecond,
+		Multiplier:      2,
+		Jitter:          0.2,
+	}
+
+	testCases := map[string]struct {
+		retry Retry
+		//failures is the number of attempts failing before the success, -1 always fails
+		failures     int
+		cancel       bool
+		wantAttempts int
+		wantErr      string
+	}{
+		"firstAttempt": {
+			retry:        r,
+			wantAttempts: 1,
+		},
+		"afterFailures": {
+			retry:        r,
+			failures:     3,
+			wantAttempts: 4,
+		},
+		"noRetries": {
+			retry:        Retry{},
+			failures:     -1,
+			wantAttempts: 1,
+			wantErr:      "unable to connect after 1 attempts, connection refused",
+		},
+		"timeout": {
+			retry:    Retry{Timeout: 100 * time.Millisecond, InitialInterval: 40 * time.Millisecond, Multiplier: 1},
+			failures: -1,
+			//the fourth attempt would start after the timeout
+			wantAttempts: 3,
+			wantErr:      "unable to connect after 3 attempts, connection refused",
+		},
+		"canceled": {
+			retry:        r,
+			failures:     -1,
+			cancel:       true,
+			wantAttempts: 1,
+			wantErr:      "unable to connect, connection refused",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			attempts := 0
+			err := tc.retry.Do(ctx, log, "connect", func(ctx context.Context) error {
+				attempts++
+				if tc.cancel {
+					cancel()
+				}
+				if tc.failures < 0 || attempts <= tc.failures {
+					return errDown
+				}
+				return nil
+			})
+			assert.Equal(t, tc.wantAttempts, attempts)
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Equal(t, tc.wantErr, err.Error())
+				assert.ErrorIs(t, err, errDown)
+			}
+		})
+	}
+}
+
+func TestInitUnreachable(t *testing.T) {
+	//a port nobody listens on
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+	l.Close()
+
+	dbc := New(
+		WithLogger(utils.LogSetup(io.Discard, "info")),
+		WithDBType("pgsql"),
+		WithPostgres(Postgres{Host: "127.0.0.1", Port: port, User: "demo", Database: "demodb"}),
+		WithRetry(Retry{Timeout: 200 * time.Millisecond, InitialInterval: 20 * time.Millisecond, Multiplier: 2}))
+	err = dbc.Init(context.Background())
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unable to connect to the database after")
+		assert.Contains(t, err.Error(), "connection refused")
+	}
+}
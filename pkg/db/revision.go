@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+	"github.com/uptrace/bun"
+)
+
+// RevisionOp is the kind of change recorded by a FruitRevision
+type RevisionOp string
+
+const (
+	// RevisionInsert records the creation of a fruit
+	RevisionInsert RevisionOp = "insert"
+	// RevisionUpdate records a change of the name, season or emoji of a fruit
+	RevisionUpdate RevisionOp = "update"
+	// RevisionDelete records the move of a fruit to the trash
+	RevisionDelete RevisionOp = "delete"
+	// RevisionRestore records the return of a fruit from the trash
+	RevisionRestore RevisionOp = "restore"
+	// RevisionRevert records the return of a fruit to an earlier revision
+	RevisionRevert RevisionOp = "revert"
+	// RevisionPurge records the permanent deletion of a fruit from the trash
+	RevisionPurge RevisionOp = "purge"
+)
+
+// SystemActor is the actor of the changes made without an actor in their context,
+// like the scheduled purge of the trash
+const SystemActor = "system"
+
+// FruitRevision records a change of a fruit, it is written in the same
+// transaction as the change
+type FruitRevision struct {
+	bun.BaseModel `bun:"table:fruit_revisions,alias:r"`
+
+	ID      int `bun:",pk,autoincrement" json:"id" example:"1"`
+	FruitID int `bun:",notnull" json:"fruit_id" example:"1"`
+	// Version is the version of the fruit after the change, or the
+	// deleted version for the deletes
+	Version   int        `bun:",notnull" json:"version" example:"2"`
+	Operation RevisionOp `bun:",notnull" json:"operation" enums:"insert,update,delete,restore,revert,purge" example:"update"`
+	Actor     string     `bun:",notnull" json:"actor" example:"alice"`
+	CreatedAt time.Time  `bun:",nullzero,notnull,default:current_timestamp" json:"created_at"`
+	// Before is the fruit before the change, it is empty for the inserts
+	Before json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	// After is the fruit after the change, it is empty for the deletes
+	After json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+}
+
+// FruitRevisions is the change history of a fruit
+type FruitRevisions []*FruitRevision
+
+type actorKey struct{}
+
+// WithActor returns the copy of ctx carrying the actor recorded in the
+// revisions of the changes made with it
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns the actor carried by ctx, SystemActor when it has none
+func Actor(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return SystemActor
+}
+
+// newRevision creates the revision of the op changing the fruit before to after,
+// either of them is nil for the inserts and the deletes
+func newRevision(ctx context.Context, op RevisionOp, before *Fruit, after *Fruit) (*FruitRevision, error) {
+	rev := &FruitRevision{
+		Operation: op,
+		Actor:     Actor(ctx),
+		CreatedAt: time.Now(),
+	}
+	var err error
+	if before != nil {
+		rev.FruitID, rev.Version = before.ID, before.Version
+		if rev.Before, err = json.Marshal(before); err != nil {
+			return nil, err
+		}
+	}
+	if after != nil {
+		rev.FruitID, rev.Version = after.ID, after.Version
+		if rev.After, err = json.Marshal(after); err != nil {
+			return nil, err
+		}
+	}
+	return rev, nil
+}
+
+// revertTo returns the fruit as it was after the revision rev having the version
+// to update, the revisions that removed the fruit could not be reverted to
+func revertTo(rev *FruitRevision, version int) (*Fruit, error) {
+	if rev.After == nil {
+		return nil, apperrors.BadRequest(fmt.Errorf("revision %d removed the fruit, revert to an earlier revision", rev.ID))
+	}
+	snapshot := &Fruit{}
+	if err := json.Unmarshal(rev.After, snapshot); err != nil {
+		return nil, err
+	}
+	return &Fruit{
+		ID:      rev.FruitID,
+		Name:    snapshot.Name,
+		Season:  snapshot.Season,
+		Emoji:   snapshot.Emoji,
+		Version: version,
+	}, nil
+}
@@ -0,0 +1,248 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+	"github.com/uptrace/bun"
+	"gopkg.in/yaml.v3"
+)
+
+// SeedMode is how Seed applies a dataset
+type SeedMode string
+
+const (
+	// SeedIfNew applies the dataset unless it was already applied, the fruits
+	// whose names are taken are left alone
+	SeedIfNew SeedMode = "if-new"
+	// SeedMerge applies the dataset even if it was already applied, the
+	// fruits whose names are taken are left alone
+	SeedMerge SeedMode = "merge"
+	// SeedReapply applies the dataset even if it was already applied, the
+	// fruits whose names are taken are set back to the dataset
+	SeedReapply SeedMode = "reapply"
+)
+
+// SeedModes are the valid SeedMode values
+var SeedModes = []SeedMode{SeedIfNew, SeedMerge, SeedReapply}
+
+// SeedRecord records the content of a dataset applied by Seed
+type SeedRecord struct {
+	bun.BaseModel `bun:"table:seeds,alias:s"`
+
+	Name string `bun:",pk"`
+	// Hash is the SHA-256 of the content of the dataset
+	Hash      string    `bun:",notnull"`
+	AppliedAt time.Time `bun:",nullzero,notnull,default:current_timestamp"`
+}
+
+// SeedOptions configures how Seed applies a dataset
+type SeedOptions struct {
+	Mode SeedMode
+	// Truncate permanently deletes all the fruits, the ones in the trash too,
+	// before the dataset is applied. It is only allowed with SeedReapply.
+	Truncate bool
+	// Validator validates each fruit of the dataset before any of them is
+	// applied, like the fruits added with the API
+	Validator interface {
+		Validate(i interface{}) error
+	}
+}
+
+// SeedResult tells what Seed did with a dataset
+type SeedResult struct {
+	Name string
+	Hash string
+	// Skipped is the reason the dataset was not applied, it is empty when it was
+	Skipped string
+	// Truncated is the number of fruits deleted before the dataset was applied
+	Truncated int
+	Inserted  int
+	Updated   int
+	// Unchanged is the number of fruits of the dataset that were left alone
+	Unchanged int
+}
+
+// seedFixture is a model of the dbfixture YAML, like the data.yaml, only the
+// name, the season and the emoji of the Fruit rows are seeded
+type seedFixture struct {
+	Model string `yaml:"model"`
+	Rows  []struct {
+		Name   string `yaml:"name"`
+		Season string `yaml:"season"`
+		Emoji  string `yaml:"emoji"`
+	} `yaml:"rows"`
+}
+
+// ParseSeed returns the fruits of the dataset in the dbfixture YAML layout
+func ParseSeed(data []byte) (Fruits, error) {
+	var fixtures []seedFixture
+	if err := yaml.Unmarshal(data, &fixtures); err != nil {
+		return nil, apperrors.BadRequest(fmt.Errorf("invalid dataset, %w", err))
+	}
+	var fruits Fruits
+	for _, fx := range fixtures {
+		if fx.Model != "Fruit" {
+			return nil, apperrors.BadRequest(fmt.Errorf("unknown model %q of the dataset, only Fruit could be seeded", fx.Model))
+		}
+		for _, r := range fx.Rows {
+			fruits = append(fruits, &Fruit{Name: r.Name, Season: r.Season, Emoji: r.Emoji})
+		}
+	}
+	return fruits, nil
+}
+
+// Seed applies the dataset data, in the dbfixture YAML layout, named name. The
+// name and the hash of the applied datasets are recorded in the seeds table so
+// that SeedIfNew could tell whether the dataset was applied. The existing fruits
+// are never deleted unless opts.Truncate is set.
+func (c *Config) Seed(ctx context.Context, name string, data []byte, opts SeedOptions) (*SeedResult, error) {
+	valid := false
+	for _, m := range SeedModes {
+		valid = valid || opts.Mode == m
+	}
+	if !valid {
+		return nil, apperrors.BadRequest(fmt.Errorf("unknown seed mode %q", opts.Mode))
+	}
+	if opts.Truncate && opts.Mode != SeedReapply {
+		return nil, apperrors.BadRequest(fmt.Errorf("truncate is only allowed with the %s mode", SeedReapply))
+	}
+	fruits, err := ParseSeed(data)
+	if err != nil {
+		return nil, err
+	}
+	for i, f := range fruits {
+		if opts.Validator != nil {
+			if err := opts.Validator.Validate(f); err != nil {
+				return nil, fmt.Errorf("fruit %d %q of the dataset is invalid, %w", i+1, f.Name, err)
+			}
+		}
+		//the season is compared to the saved one the way it is saved
+		f.Season = CanonicalSeason(f.Season)
+	}
+
+	sum := sha256.Sum256(data)
+	res := &SeedResult{Name: name, Hash: hex.EncodeToString(sum[:])}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err = c.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		applied := &SeedRecord{Name: name}
+		err := tx.NewSelect().
+			Model(applied).
+			WherePK().
+			Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			applied = nil
+		} else if err != nil {
+			return err
+		}
+		if opts.Mode == SeedIfNew && applied != nil {
+			if applied.Hash == res.Hash {
+				res.Skipped = fmt.Sprintf("already applied at %s", applied.AppliedAt.Format(time.RFC3339))
+			} else {
+				res.Skipped = fmt.Sprintf("another content was applied at %s, use the %s or %s mode to apply it",
+					applied.AppliedAt.Format(time.RFC3339), SeedMerge, SeedReapply)
+			}
+			return nil
+		}
+
+		if opts.Truncate {
+			if res.Truncated, err = truncateFruits(ctx, tx); err != nil {
+				return err
+			}
+		}
+		for _, f := range fruits {
+			if err := seedFruit(ctx, tx, f, opts.Mode, res); err != nil {
+				return err
+			}
+		}
+
+		record := &SeedRecord{Name: name, Hash: res.Hash, AppliedAt: time.Now()}
+		if applied == nil {
+			_, err = tx.NewInsert().
+				Model(record).
+				Exec(ctx)
+		} else {
+			_, err = tx.NewUpdate().
+				Model(record).
+				Column("hash", "applied_at").
+				WherePK().
+				Exec(ctx)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SeedRecords returns the applied datasets ordered by their names
+func (c *Config) SeedRecords(ctx context.Context) ([]SeedRecord, error) {
+	var records []SeedRecord
+	err := c.DB.NewSelect().
+		Model(&records).
+		OrderExpr("? ASC", bun.Ident("name")).
+		Scan(ctx)
+	return records, err
+}
+
+// seedFruit inserts the fruit f of a dataset when its name is not taken, or
+// sets the fruit having its name back to it with SeedReapply
+func seedFruit(ctx context.Context, tx bun.Tx, f *Fruit, mode SeedMode, res *SeedResult) error {
+	existing := &Fruit{}
+	err := tx.NewSelect().
+		Model(existing).
+		Where("UPPER(?) = ?", bun.Ident("name"), strings.ToUpper(f.Name)).
+		Limit(1).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		res.Inserted++
+		return insertFruit(ctx, tx, f)
+	}
+	if err != nil {
+		return err
+	}
+	if mode != SeedReapply || (existing.Name == f.Name && existing.Season == f.Season && existing.Emoji == f.Emoji) {
+		res.Unchanged++
+		return nil
+	}
+	res.Updated++
+	f.ID = existing.ID
+	return updateFruit(ctx, tx, f, RevisionUpdate)
+}
+
+// truncateFruits permanently deletes all the fruits, the ones in the trash too,
+// their deletion is recorded in their history like the purge of the trash
+func truncateFruits(ctx context.Context, tx bun.Tx) (int, error) {
+	var fruits Fruits
+	if err := tx.NewSelect().
+		Model(&fruits).
+		WhereAllWithDeleted().
+		Scan(ctx); err != nil || len(fruits) == 0 {
+		return 0, err
+	}
+	for _, f := range fruits {
+		if err := recordRevision(ctx, tx, RevisionPurge, f, nil); err != nil {
+			return 0, err
+		}
+	}
+	_, err := tx.NewDelete().
+		Model((*Fruit)(nil)).
+		WhereAllWithDeleted().
+		//bun refuses the deletes without a where clause
+		Where("1 = 1").
+		ForceDelete().
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return len(fruits), nil
+}
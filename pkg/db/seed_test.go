@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+const seedData = `- model: Fruit
+  rows:
+    - _id: mango
+      name: Mango
+      season: Spring
+      emoji: U+1F96D
+    - _id: apple
+      name: Apple
+      season: Fall
+      emoji: U+1F34E
+`
+
+// seasonValidator rejects the fruits without a season
+type seasonValidator struct{}
+
+func (seasonValidator) Validate(i interface{}) error {
+	if i.(*Fruit).Season == "" {
+		return errors.New("season is required")
+	}
+	return nil
+}
+
+// newSeedDB creates a sqlite database of its own, the opts override the defaults
+func newSeedDB(t *testing.T, ctx context.Context, opts ...Option) *Config {
+	log := utils.LogSetup(os.Stdout, utils.LookupEnvOrString("TEST_LOG_LEVEL", "info"))
+	dbc := New(append([]Option{
+		WithLogger(log),
+		WithDBType("sqlite"),
+		WithDBFile(path.Join(t.TempDir(), "seed.db"))}, opts...)...)
+	if err := dbc.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return dbc
+}
+
+func TestSeed(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dbc := newSeedDB(t, ctx)
+	repo := NewBunFruitRepository(dbc)
+
+	res, err := dbc.Seed(ctx, "data", []byte(seedData), SeedOptions{Mode: SeedIfNew, Validator: seasonValidator{}})
+	if assert.NoError(t, err) {
+		assert.Empty(t, res.Skipped)
+		assert.Equal(t, 2, res.Inserted)
+		assert.Len(t, res.Hash, 64)
+	}
+
+	res, err = dbc.Seed(ctx, "data", []byte(seedData), SeedOptions{Mode: SeedIfNew})
+	if assert.NoError(t, err) {
+		assert.Contains(t, res.Skipped, "already applied at")
+		assert.Zero(t, res.Inserted)
+	}
+
+	//users change the seeded fruits and add their own
+	mango, err := repo.Get(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mango.Season = "Summer"
+	if err := repo.Update(ctx, mango); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Create(ctx, &Fruit{Name: "Pear", Season: "Fall"}); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := seedData + `    - _id: banana
+      name: Banana
+      season: Summer
+`
+	res, err = dbc.Seed(ctx, "data", []byte(changed), SeedOptions{Mode: SeedIfNew})
+	if assert.NoError(t, err) {
+		assert.Contains(t, res.Skipped, "another content was applied at")
+	}
+
+	res, err = dbc.Seed(ctx, "data", []byte(changed), SeedOptions{Mode: SeedMerge})
+	if assert.NoError(t, err) {
+		assert.Empty(t, res.Skipped)
+		assert.Equal(t, SeedResult{Name: "data", Hash: res.Hash, Inserted: 1, Unchanged: 2}, *res)
+	}
+	mango, err = repo.Get(ctx, 1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Summer", mango.Season, "Expecting merge to leave the changed fruits alone")
+	}
+
+	res, err = dbc.Seed(WithActor(ctx, "seed"), "data", []byte(changed), SeedOptions{Mode: SeedReapply})
+	if assert.NoError(t, err) {
+		assert.Equal(t, SeedResult{Name: "data", Hash: res.Hash, Updated: 1, Unchanged: 2}, *res)
+	}
+	mango, err = repo.Get(ctx, 1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Spring", mango.Season, "Expecting reapply to set the fruit back to the dataset")
+	}
+	all, _, err := repo.List(ctx, ListOptions{})
+	if assert.NoError(t, err) {
+		assert.ElementsMatch(t, []string{"Mango", "Apple", "Pear", "Banana"}, names(all), "Expecting the fruits of the users to be kept")
+	}
+
+	res, err = dbc.Seed(WithActor(ctx, "seed"), "data", []byte(seedData), SeedOptions{Mode: SeedReapply, Truncate: true})
+	if assert.NoError(t, err) {
+		assert.Equal(t, SeedResult{Name: "data", Hash: res.Hash, Truncated: 4, Inserted: 2}, *res)
+	}
+	all, _, err = repo.List(ctx, ListOptions{})
+	if assert.NoError(t, err) {
+		assert.ElementsMatch(t, []string{"Mango", "Apple"}, names(all))
+	}
+	history, err := repo.History(ctx, 3)
+	if assert.NoError(t, err) && assert.NotEmpty(t, history) {
+		last := history[len(history)-1]
+		assert.Equal(t, RevisionPurge, last.Operation, "Expecting the truncated fruits to have their purge recorded")
+		assert.Equal(t, "seed", last.Actor)
+	}
+
+	records, err := dbc.SeedRecords(ctx)
+	if assert.NoError(t, err) && assert.Len(t, records, 1) {
+		assert.Equal(t, "data", records[0].Name)
+		assert.Equal(t, res.Hash, records[0].Hash)
+	}
+}
+
+func TestSeedErrors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dbc := newSeedDB(t, ctx)
+
+	testCases := map[string]struct {
+		data    string
+		opts    SeedOptions
+		wantErr string
+	}{
+		"unknownMode": {
+			data:    seedData,
+			opts:    SeedOptions{Mode: "replace"},
+			wantErr: `unknown seed mode "replace"`,
+		},
+		"truncateWithoutReapply": {
+			data:    seedData,
+			opts:    SeedOptions{Mode: SeedMerge, Truncate: true},
+			wantErr: "truncate is only allowed with the reapply mode",
+		},
+		"unknownModel": {
+			data:    "- model: Vegetable\n  rows:\n    - name: Carrot\n",
+			opts:    SeedOptions{Mode: SeedIfNew},
+			wantErr: `unknown model "Vegetable" of the dataset, only Fruit could be seeded`,
+		},
+		"invalidFruit": {
+			data:    "- model: Fruit\n  rows:\n    - name: Kiwi\n",
+			opts:    SeedOptions{Mode: SeedIfNew, Validator: seasonValidator{}},
+			wantErr: `fruit 1 "Kiwi" of the dataset is invalid, season is required`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := dbc.Seed(ctx, "data", []byte(tc.data), tc.opts)
+			if assert.Error(t, err) {
+				assert.Equal(t, tc.wantErr, err.Error())
+			}
+		})
+	}
+
+	n, err := dbc.DB.NewSelect().Model((*Fruit)(nil)).Count(ctx)
+	if assert.NoError(t, err) {
+		assert.Zero(t, n, "Expecting the invalid datasets not to be applied")
+	}
+	records, err := dbc.SeedRecords(ctx)
+	if assert.NoError(t, err) {
+		assert.Empty(t, records)
+	}
+}
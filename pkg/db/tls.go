@@ -0,0 +1,102 @@
+package db
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLSMode is how the pgsql and mysql connections are encrypted, the modes
+// are named after the sslmode of PostgreSQL
+type TLSMode string
+
+const (
+	// TLSDisable does not encrypt the connections
+	TLSDisable TLSMode = "disable"
+	// TLSRequire encrypts the connections without verifying the server certificate
+	TLSRequire TLSMode = "require"
+	// TLSVerifyCA encrypts the connections and verifies that the server
+	// certificate is signed by the CA
+	TLSVerifyCA TLSMode = "verify-ca"
+	// TLSVerifyFull encrypts the connections and verifies that the server
+	// certificate is signed by the CA and issued to the server host
+	TLSVerifyFull TLSMode = "verify-full"
+)
+
+// TLSModes are the valid TLSMode values
+var TLSModes = []TLSMode{TLSDisable, TLSRequire, TLSVerifyCA, TLSVerifyFull}
+
+// TLS configures the encryption of the pgsql and mysql connections
+type TLS struct {
+	Mode TLSMode
+	// CAFile is the PEM file of the CA verifying the server certificate, the
+	// system CAs are used when it is empty
+	CAFile string
+	// CertFile and KeyFile are the PEM files of the client certificate,
+	// used by the servers authenticating the clients by their certificates
+	CertFile string
+	KeyFile  string
+	// ServerName is the name the server certificate is verified against by
+	// TLSVerifyFull, it defaults to the host the connection is made to
+	ServerName string
+}
+
+// Config returns the tls.Config of the connections to host, it is nil when
+// the connections are not encrypted
+func (t TLS) Config(host string) (*tls.Config, error) {
+	if t.Mode == "" || t.Mode == TLSDisable {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if t.CertFile != "" || t.KeyFile != "" {
+		if t.CertFile == "" || t.KeyFile == "" {
+			return nil, errors.New("both the client certificate and its key are required")
+		}
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the client certificate, %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the CA file, %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in the CA file %s", t.CAFile)
+		}
+	}
+
+	switch t.Mode {
+	case TLSRequire:
+		cfg.InsecureSkipVerify = true
+	case TLSVerifyCA:
+		//the chain is verified without the host name, which the tls package
+		//only does when it skips the whole verification
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			opts := x509.VerifyOptions{
+				Roots:         cfg.RootCAs,
+				Intermediates: x509.NewCertPool(),
+			}
+			for _, cert := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err := cs.PeerCertificates[0].Verify(opts)
+			return err
+		}
+	case TLSVerifyFull:
+		cfg.ServerName = t.ServerName
+		if cfg.ServerName == "" {
+			cfg.ServerName = host
+		}
+	default:
+		return nil, fmt.Errorf("unknown TLS mode %q", t.Mode)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,242 @@
+package db
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCert is a certificate with its key, written as PEM files
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// newTestCert creates a certificate for the dnsNames signed by the parent,
+// or a CA when the parent is nil
+func newTestCert(t *testing.T, name string, parent *testCert, dnsNames ...string) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     dnsNames,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &testCert{
+		cert:     cert,
+		key:      key,
+		certFile: path.Join(t.TempDir(), name+".crt"),
+		keyFile:  path.Join(t.TempDir(), name+".key"),
+	}
+	if err := os.WriteFile(c.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(c.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestTLSConfig(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	server := newTestCert(t, "server", ca, "db.example.com")
+	client := newTestCert(t, "client", ca)
+	serverCert, err := tls.LoadX509KeyPair(server.certFile, server.keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	testCases := map[string]struct {
+		tls TLS
+		//host is the host the client connects to
+		host string
+		//clientAuth makes the server require the client certificate
+		clientAuth bool
+		wantErr    string
+	}{
+		"require": {
+			tls:  TLS{Mode: TLSRequire},
+			host: "127.0.0.1",
+		},
+		"verifyCAIgnoresTheHost": {
+			tls:  TLS{Mode: TLSVerifyCA, CAFile: ca.certFile},
+			host: "127.0.0.1",
+		},
+		"verifyCAUnknownCA": {
+			tls:     TLS{Mode: TLSVerifyCA},
+			host:    "127.0.0.1",
+			wantErr: "certificate signed by unknown authority",
+		},
+		"verifyFull": {
+			tls:  TLS{Mode: TLSVerifyFull, CAFile: ca.certFile},
+			host: "db.example.com",
+		},
+		"verifyFullServerName": {
+			tls:  TLS{Mode: TLSVerifyFull, CAFile: ca.certFile, ServerName: "db.example.com"},
+			host: "127.0.0.1",
+		},
+		"verifyFullWrongHost": {
+			tls:     TLS{Mode: TLSVerifyFull, CAFile: ca.certFile},
+			host:    "other.example.com",
+			wantErr: "certificate is valid for db.example.com, not other.example.com",
+		},
+		"clientCert": {
+			tls:        TLS{Mode: TLSVerifyFull, CAFile: ca.certFile, CertFile: client.certFile, KeyFile: client.keyFile},
+			host:       "db.example.com",
+			clientAuth: true,
+		},
+		"missingClientCert": {
+			tls:        TLS{Mode: TLSVerifyFull, CAFile: ca.certFile},
+			host:       "db.example.com",
+			clientAuth: true,
+			wantErr:    "certificate required",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := tc.tls.Config(tc.host)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			serverConfig := &tls.Config{Certificates: []tls.Certificate{serverCert}}
+			if tc.clientAuth {
+				serverConfig.ClientAuth = tls.RequireAndVerifyClientCert
+				serverConfig.ClientCAs = clientCAs
+			}
+			l, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+			go func() {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				//the client learns that its certificate is rejected on its first read
+				_ = conn.(*tls.Conn).Handshake()
+				_, _ = conn.Write([]byte("ok"))
+			}()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			raw, err := (&net.Dialer{}).DialContext(ctx, "tcp", l.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn := tls.Client(raw, cfg)
+			defer conn.Close()
+			err = conn.HandshakeContext(ctx)
+			if err == nil {
+				_, err = conn.Read(make([]byte, 2))
+			}
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	testCases := map[string]struct {
+		tls     TLS
+		wantErr string
+	}{
+		"unknownMode": {
+			tls:     TLS{Mode: "prefer"},
+			wantErr: `unknown TLS mode "prefer"`,
+		},
+		"certWithoutKey": {
+			tls:     TLS{Mode: TLSRequire, CertFile: ca.certFile},
+			wantErr: "both the client certificate and its key are required",
+		},
+		"missingCAFile": {
+			tls:     TLS{Mode: TLSVerifyFull, CAFile: path.Join(t.TempDir(), "ca.crt")},
+			wantErr: "unable to read the CA file",
+		},
+		"notACAFile": {
+			tls:     TLS{Mode: TLSVerifyFull, CAFile: ca.keyFile},
+			wantErr: "no certificates found in the CA file",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := tc.tls.Config("localhost")
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.wantErr)
+			}
+		})
+	}
+
+	cfg, err := TLS{Mode: TLSDisable}.Config("localhost")
+	assert.NoError(t, err)
+	assert.Nil(t, cfg, "Expecting no TLS config when it is disabled")
+}
+
+func TestPool(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	log := utils.LogSetup(os.Stdout, utils.LookupEnvOrString("TEST_LOG_LEVEL", "info"))
+	dbc := New(
+		WithLogger(log),
+		WithDBType("sqlite"),
+		WithDBFile(path.Join(t.TempDir(), "pool.db")),
+		WithAutoMigrate(false),
+		WithPool(Pool{MaxOpenConns: 4, MaxIdleConns: 1}))
+	if err := dbc.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer dbc.DB.Close()
+
+	assert.Equal(t, 4, dbc.DB.Stats().MaxOpenConnections)
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/uptrace/bun"
@@ -12,12 +13,32 @@ import (
 type Fruit struct {
 	bun.BaseModel `bun:"table:fruits,alias:f"`
 
-	ID         int       `bun:",pk,autoincrement,nullzero" json:"id"`
-	Name       string    `bun:",notnull" json:"name" `
-	Season     string    `bun:",notnull" json:"season"`
-	Emoji      string    `bun:"," json:"emoji,omitempty"`
-	CreatedAt  time.Time `bun:",nullzero,notnull,default:current_timestamp" json:"-"`
-	ModifiedAt time.Time `json:"-"`
+	ID         int       `bun:",pk,autoincrement,nullzero" json:"id" query:"filter,sort"`
+	Name       string    `bun:",notnull" json:"name" query:"filter,sort" validate:"required,max=64"`
+	Season     string    `bun:",notnull" json:"season" query:"filter,sort" validate:"required,season"`
+	Emoji      string    `bun:"," json:"emoji,omitempty" query:"filter" validate:"omitempty,emoji"`
+	CreatedAt  time.Time `bun:",nullzero,notnull,default:current_timestamp" json:"-" query:"filter,sort"`
+	ModifiedAt time.Time `json:"-" query:"filter,sort"`
+	// Version is incremented on each update of the fruit, it is used as
+	// the fruit ETag to detect concurrent updates
+	Version int `bun:",nullzero,notnull,default:1" json:"-"`
+	// DeletedAt is the time the fruit was moved to the trash, the deleted
+	// fruits are excluded from the queries unless asked for
+	DeletedAt *time.Time `bun:",soft_delete,nullzero" json:"deleted_at,omitempty"`
+}
+
+// Seasons are the known seasons of a Fruit
+var Seasons = []string{"Spring", "Summer", "Fall", "Winter"}
+
+// CanonicalSeason returns the season s spelled as in the Seasons ignoring the
+// case, s is returned as is when it is not one of them
+func CanonicalSeason(s string) string {
+	for _, season := range Seasons {
+		if strings.EqualFold(season, s) {
+			return season
+		}
+	}
+	return s
 }
 
 // Fruits represents a collection of Fruits
@@ -0,0 +1,176 @@
+// Package metrics exposes the Prometheus metrics of the Fruits API.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/uptrace/bun"
+)
+
+const (
+	namespace = "fruits"
+	// unmatchedRoute is the route label of the requests that matched no route,
+	// their paths are not used as label to keep the number of series bounded
+	unmatchedRoute = "unmatched"
+	// scrapeTimeout is the maximum time to count the fruits on a scrape
+	scrapeTimeout = 5 * time.Second
+)
+
+// Metrics holds the Prometheus collectors of the application
+type Metrics struct {
+	// Registry is the registry holding all the collectors
+	Registry *prometheus.Registry
+
+	httpRequests  *prometheus.CounterVec
+	httpDuration  *prometheus.HistogramVec
+	queryDuration *prometheus.HistogramVec
+}
+
+// New creates the Metrics of the application along with the Go runtime and
+// process collectors
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "The number of HTTP requests handled by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "The time taken to handle the HTTP requests by route, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "The time taken by the database queries by operation and status, ok or error.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"operation", "status"}),
+	}
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.queryDuration,
+	)
+	return m
+}
+
+// Handler returns the handler serving the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{})
+}
+
+// Middleware creates the middleware that counts and times the requests, it must
+// be added after the middleware handling the errors so that the status is the
+// one of the response
+func (m *Metrics) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			status := c.Response().Status
+			if he, ok := err.(*echo.HTTPError); ok {
+				status = he.Code
+			}
+			route := c.Path()
+			//echo uses the request path as the path of the requests matching no route
+			if status == http.StatusNotFound && route == c.Request().URL.Path {
+				route = unmatchedRoute
+			}
+			labels := prometheus.Labels{
+				"route":  route,
+				"method": c.Request().Method,
+				"status": strconv.Itoa(status),
+			}
+			m.httpRequests.With(labels).Inc()
+			m.httpDuration.With(labels).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
+
+// QueryHook returns the bun query hook timing the database queries
+func (m *Metrics) QueryHook() bun.QueryHook {
+	return &queryHook{m.queryDuration}
+}
+
+type queryHook struct {
+	duration *prometheus.HistogramVec
+}
+
+var _ bun.QueryHook = (*queryHook)(nil)
+
+// BeforeQuery implements bun.QueryHook
+func (h *queryHook) BeforeQuery(ctx context.Context, event *bun.QueryEvent) context.Context {
+	return ctx
+}
+
+// AfterQuery implements bun.QueryHook
+func (h *queryHook) AfterQuery(ctx context.Context, event *bun.QueryEvent) {
+	status := "ok"
+	switch event.Err {
+	case nil, sql.ErrNoRows:
+	default:
+		status = "error"
+	}
+	h.duration.
+		WithLabelValues(event.Operation(), status).
+		Observe(time.Since(event.StartTime).Seconds())
+}
+
+// RegisterDB adds the connection pool statistics of the database and the number
+// of fruits per season counted from the repository on each scrape
+func (m *Metrics) RegisterDB(dbc *db.Config, repo db.FruitRepository) {
+	m.Registry.MustRegister(
+		collectors.NewDBStatsCollector(dbc.DB.DB, dbc.DBType.String()),
+		&seasonCollector{repo: repo},
+	)
+}
+
+var fruitsPerSeason = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "per_season"),
+	"The number of fruits of each season, the fruits in the trash are not counted.",
+	[]string{"season"}, nil)
+
+// seasonCollector counts the fruits of each season
+type seasonCollector struct {
+	repo db.FruitRepository
+}
+
+var _ prometheus.Collector = (*seasonCollector)(nil)
+
+// Describe implements prometheus.Collector
+func (c *seasonCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- fruitsPerSeason
+}
+
+// Collect implements prometheus.Collector
+func (c *seasonCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+	counts, err := c.repo.CountBySeason(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(fruitsPerSeason, err)
+		return
+	}
+	//the seasons without fruits are reported too
+	for _, season := range db.Seasons {
+		ch <- prometheus.MustNewConstMetric(fruitsPerSeason, prometheus.GaugeValue, float64(counts[season]), season)
+	}
+}
@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"github.com/kameshsampath/go-fruits-api/pkg/routes"
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	log := utils.LogSetup(io.Discard, "info")
+	m := New()
+	e := echo.New()
+	e.HTTPErrorHandler = routes.NewHTTPErrorHandler(log)
+	e.Use(m.Middleware(), routes.AccessLog(log))
+	e.GET("/api/fruits/:id", func(c echo.Context) error {
+		if c.Param("id") == "42" {
+			return apperrors.NotFound("fruit", 42)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	for _, target := range []string{"/api/fruits/1", "/api/fruits/2", "/api/fruits/42", "/api/vegetables/1"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.httpRequests.WithLabelValues("/api/fruits/:id", http.MethodGet, "200")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.httpRequests.WithLabelValues("/api/fruits/:id", http.MethodGet, "404")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.httpRequests.WithLabelValues(unmatchedRoute, http.MethodGet, "404")))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.httpDuration), "Expecting a histogram per route, method and status")
+}
+
+func TestRegisterDB(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	log := utils.LogSetup(os.Stdout, utils.LookupEnvOrString("TEST_LOG_LEVEL", "info"))
+	dbc := db.New(
+		db.WithLogger(log),
+		db.WithDBType("sqlite"),
+		db.WithDBFile(path.Join(t.TempDir(), "metrics.db")))
+	if err := dbc.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	m := New()
+	dbc.DB.AddQueryHook(m.QueryHook())
+	repo := db.NewBunFruitRepository(dbc)
+	m.RegisterDB(dbc, repo)
+
+	for _, f := range []*db.Fruit{
+		{Name: "Mango", Season: "Spring"},
+		{Name: "Apple", Season: "Fall"},
+		{Name: "Pear", Season: "fall"},
+	} {
+		if err := repo.Create(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.Delete(ctx, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `
+# HELP fruits_per_season The number of fruits of each season, the fruits in the trash are not counted.
+# TYPE fruits_per_season gauge
+fruits_per_season{season="Fall"} 2
+fruits_per_season{season="Spring"} 0
+fruits_per_season{season="Summer"} 0
+fruits_per_season{season="Winter"} 0
+`
+	assert.NoError(t, testutil.GatherAndCompare(m.Registry, strings.NewReader(want), "fruits_per_season"))
+
+	mfs, err := m.Registry.Gather()
+	if assert.NoError(t, err) {
+		var names []string
+		for _, mf := range mfs {
+			names = append(names, mf.GetName())
+			if mf.GetName() != "fruits_db_query_duration_seconds" {
+				continue
+			}
+			for _, metric := range mf.GetMetric() {
+				labels := map[string]string{}
+				for _, l := range metric.GetLabel() {
+					labels[l.GetName()] = l.GetValue()
+				}
+				if labels["operation"] == "INSERT" && labels["status"] == "ok" {
+					//the fruits along with the revisions of the inserts and the delete
+					assert.Equal(t, uint64(7), metric.GetHistogram().GetSampleCount())
+				}
+			}
+		}
+		assert.Subset(t, names, []string{
+			"go_sql_open_connections",
+			"go_sql_idle_connections",
+			"go_sql_in_use_connections",
+			"go_sql_wait_count_total",
+			"fruits_db_query_duration_seconds",
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kind is the kind of values a Field holds
+type Kind int
+
+const (
+	//String fields are compared case insensitively
+	String Kind = iota
+	//Number fields hold integer values
+	Number
+	//Time fields hold timestamps
+	Time
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Field is a model attribute that could be used in filter and sort expressions
+type Field struct {
+	//Name is the column name of the field
+	Name       string
+	Kind       Kind
+	Filterable bool
+	Sortable   bool
+	index      []int
+}
+
+// Value returns the value of the field in the model which must be a pointer
+// to the struct the field was read from
+func (f *Field) Value(model interface{}) interface{} {
+	return reflect.Indirect(reflect.ValueOf(model)).FieldByIndex(f.index).Interface()
+}
+
+// Convert converts the JSON decoded value v to the Go value of the field
+func (f *Field) Convert(v interface{}) (interface{}, error) {
+	switch f.Kind {
+	case Number:
+		if n, ok := v.(float64); ok {
+			return int64(n), nil
+		}
+	case Time:
+		if s, ok := v.(string); ok {
+			return ParseTime(s)
+		}
+	default:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid value %v for field %q", v, f.Name)
+}
+
+// Fields are the queryable fields of a model keyed by the column name
+type Fields map[string]*Field
+
+// FieldsOf builds the Fields of the model struct from the `query` struct tags.
+// A field tagged `query:"filter,sort"` could be used in filter and sort
+// expressions, the column name is taken from the `bun` tag
+func FieldsOf(model interface{}) Fields {
+	fs := Fields{}
+	t := reflect.TypeOf(model)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("query")
+		if !ok {
+			continue
+		}
+		f := &Field{
+			Name:  columnName(sf),
+			index: sf.Index,
+		}
+		switch {
+		case sf.Type == timeType:
+			f.Kind = Time
+		case sf.Type.Kind() == reflect.String:
+			f.Kind = String
+		default:
+			f.Kind = Number
+		}
+		for _, o := range strings.Split(tag, ",") {
+			switch strings.TrimSpace(o) {
+			case "filter":
+				f.Filterable = true
+			case "sort":
+				f.Sortable = true
+			}
+		}
+		fs[f.Name] = f
+	}
+	return fs
+}
+
+// Names returns the sorted names of the fields that match the predicate
+func (fs Fields) Names(match func(*Field) bool) []string {
+	var names []string
+	for n, f := range fs {
+		if match(f) {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func columnName(sf reflect.StructField) string {
+	if tag := sf.Tag.Get("bun"); tag != "" {
+		if n := strings.Split(tag, ",")[0]; n != "" {
+			return n
+		}
+	}
+	return underscore(sf.Name)
+}
+
+// underscore converts "CamelCasedString" to "camel_cased_string" the same
+// way bun names the columns
+func underscore(s string) string {
+	isUpper := func(c byte) bool { return c >= 'A' && c <= 'Z' }
+	isLower := func(c byte) bool { return c >= 'a' && c <= 'z' }
+	r := make([]byte, 0, len(s)+5)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isUpper(c) {
+			if i > 0 && i+1 < len(s) && (isLower(s[i-1]) || isLower(s[i+1])) {
+				r = append(r, '_', c+32)
+			} else {
+				r = append(r, c+32)
+			}
+		} else {
+			r = append(r, c)
+		}
+	}
+	return string(r)
+}
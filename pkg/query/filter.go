@@ -0,0 +1,473 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Op is a comparison operator of the filter expression
+type Op string
+
+const (
+	//Eq is equal to
+	Eq Op = "eq"
+	//Ne is not equal to
+	Ne Op = "ne"
+	//Gt is greater than
+	Gt Op = "gt"
+	//Ge is greater than or equal to
+	Ge Op = "ge"
+	//Lt is less than
+	Lt Op = "lt"
+	//Le is less than or equal to
+	Le Op = "le"
+	//Like matches the string fields containing the value
+	Like Op = "like"
+)
+
+var sqlOps = map[Op]string{
+	Eq: "=",
+	Ne: "<>",
+	Gt: ">",
+	Ge: ">=",
+	Lt: "<",
+	Le: "<=",
+}
+
+// Expr is a node of the parsed filter expression
+type Expr interface {
+	//AppendSQL appends the SQL condition of the node to b, the values
+	//and column identifiers are added as bun query arguments
+	AppendSQL(b []byte, args []interface{}) ([]byte, []interface{})
+	//Match evaluates the node against the model, it matches the same
+	//models as the SQL condition
+	Match(model interface{}) bool
+}
+
+// And matches when both the expressions match
+type And struct {
+	Left, Right Expr
+}
+
+// Or matches when either of the expressions match
+type Or struct {
+	Left, Right Expr
+}
+
+// Not matches when the expression does not match
+type Not struct {
+	Expr Expr
+}
+
+// Comparison compares the Field with the Value using the Op
+type Comparison struct {
+	Field *Field
+	Op    Op
+	Value interface{}
+}
+
+var (
+	_ Expr = (*And)(nil)
+	_ Expr = (*Or)(nil)
+	_ Expr = (*Not)(nil)
+	_ Expr = (*Comparison)(nil)
+)
+
+// AppendSQL implements Expr
+func (e *And) AppendSQL(b []byte, args []interface{}) ([]byte, []interface{}) {
+	return appendBinary(b, args, e.Left, " AND ", e.Right)
+}
+
+// AppendSQL implements Expr
+func (e *Or) AppendSQL(b []byte, args []interface{}) ([]byte, []interface{}) {
+	return appendBinary(b, args, e.Left, " OR ", e.Right)
+}
+
+// AppendSQL implements Expr
+func (e *Not) AppendSQL(b []byte, args []interface{}) ([]byte, []interface{}) {
+	b = append(b, "NOT ("...)
+	b, args = e.Expr.AppendSQL(b, args)
+	return append(b, ')'), args
+}
+
+// AppendSQL implements Expr
+func (e *Comparison) AppendSQL(b []byte, args []interface{}) ([]byte, []interface{}) {
+	col := bun.Ident(e.Field.Name)
+	switch {
+	case e.Op == Like:
+		b = append(b, "UPPER(?) LIKE ? ESCAPE '!'"...)
+		args = append(args, col, "%"+escapeLike(strings.ToUpper(e.Value.(string)))+"%")
+	case e.Field.Kind == String && (e.Op == Eq || e.Op == Ne):
+		b = append(b, "UPPER(?) "+sqlOps[e.Op]+" ?"...)
+		args = append(args, col, strings.ToUpper(e.Value.(string)))
+	default:
+		b = append(b, "? "+sqlOps[e.Op]+" ?"...)
+		args = append(args, col, e.Value)
+	}
+	return b, args
+}
+
+// Match implements Expr
+func (e *And) Match(model interface{}) bool {
+	return e.Left.Match(model) && e.Right.Match(model)
+}
+
+// Match implements Expr
+func (e *Or) Match(model interface{}) bool {
+	return e.Left.Match(model) || e.Right.Match(model)
+}
+
+// Match implements Expr
+func (e *Not) Match(model interface{}) bool {
+	return !e.Expr.Match(model)
+}
+
+// Match implements Expr
+func (e *Comparison) Match(model interface{}) bool {
+	v := e.Field.Value(model)
+	if e.Field.Kind == String {
+		s, want := strings.ToUpper(v.(string)), strings.ToUpper(e.Value.(string))
+		switch e.Op {
+		case Like:
+			return strings.Contains(s, want)
+		case Eq, Ne:
+			return compareOp(e.Op, Compare(s, want))
+		}
+	}
+	return compareOp(e.Op, Compare(v, e.Value))
+}
+
+// Compare compares the field values a and b of the same kind, it returns
+// -1, 0 or +1 when a is less than, equal to or greater than b
+func Compare(a, b interface{}) int {
+	switch av := a.(type) {
+	case string:
+		return strings.Compare(av, b.(string))
+	case time.Time:
+		bt := b.(time.Time)
+		switch {
+		case av.Before(bt):
+			return -1
+		case av.After(bt):
+			return 1
+		}
+		return 0
+	default:
+		an, bn := toInt64(a), toInt64(b)
+		switch {
+		case an < bn:
+			return -1
+		case an > bn:
+			return 1
+		}
+		return 0
+	}
+}
+
+func toInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	case uint:
+		return int64(n)
+	case uint64:
+		return int64(n)
+	case float64:
+		return int64(n)
+	}
+	return 0
+}
+
+func compareOp(op Op, c int) bool {
+	switch op {
+	case Eq:
+		return c == 0
+	case Ne:
+		return c != 0
+	case Gt:
+		return c > 0
+	case Ge:
+		return c >= 0
+	case Lt:
+		return c < 0
+	case Le:
+		return c <= 0
+	}
+	return false
+}
+
+func appendBinary(b []byte, args []interface{}, left Expr, sep string, right Expr) ([]byte, []interface{}) {
+	b = append(b, '(')
+	b, args = left.AppendSQL(b, args)
+	b = append(b, sep...)
+	b, args = right.AppendSQL(b, args)
+	return append(b, ')'), args
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// Where applies the filter expression e to the query, a nil e leaves the query as is
+func Where(q *bun.SelectQuery, e Expr) *bun.SelectQuery {
+	if e == nil {
+		return q
+	}
+	b, args := e.AppendSQL(nil, nil)
+	return q.Where(string(b), args...)
+}
+
+// ParseFilter parses the filter expression s such as
+// `season eq 'Summer' and name like 'ber'` into an Expr. Only the Filterable
+// fields could be used in the expression.
+//
+//	expr       = term { "or" term }
+//	term       = factor { "and" factor }
+//	factor     = "not" factor | "(" expr ")" | comparison
+//	comparison = field op value
+//	op         = "eq" | "ne" | "gt" | "ge" | "lt" | "le" | "like"
+//	value      = "'" string "'" | number
+func ParseFilter(s string, fields Fields) (Expr, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, fields: fields}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at position %d", t, t.pos)
+	}
+	return e, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of filter"
+	case tokenString:
+		return fmt.Sprintf("'%s'", t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case c == '\'':
+			var sb strings.Builder
+			start := i
+			i++
+			for {
+				if i >= len(s) {
+					return nil, fmt.Errorf("unterminated string at position %d", start)
+				}
+				if s[i] == '\'' {
+					//a quote is escaped by doubling it
+					if i+1 < len(s) && s[i+1] == '\'' {
+						sb.WriteByte('\'')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				sb.WriteByte(s[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenString, text: sb.String(), pos: start})
+		case c == '-' || (c >= '0' && c <= '9'):
+			start := i
+			i++
+			for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: s[start:i], pos: start})
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			start := i
+			for i < len(s) && (s[i] == '_' || (s[i] >= 'a' && s[i] <= 'z') || (s[i] >= 'A' && s[i] <= 'Z') || (s[i] >= '0' && s[i] <= '9')) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: s[start:i], pos: start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(s)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	fields Fields
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) keyword(kw string) bool {
+	t := p.peek()
+	if t.kind == tokenIdent && strings.EqualFold(t.text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseFactor() (Expr, error) {
+	if p.keyword("not") {
+		e, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: e}, nil
+	}
+	if p.peek().kind == tokenLParen {
+		p.next()
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokenRParen {
+			return nil, fmt.Errorf("expecting ')' but got %s at position %d", t, t.pos)
+		}
+		return e, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	t := p.next()
+	if t.kind != tokenIdent {
+		return nil, fmt.Errorf("expecting a field but got %s at position %d", t, t.pos)
+	}
+	f, ok := p.fields[t.text]
+	if !ok || !f.Filterable {
+		return nil, fmt.Errorf("unknown filter field %q at position %d, allowed fields are %s",
+			t.text, t.pos, strings.Join(p.fields.Names(func(f *Field) bool { return f.Filterable }), ","))
+	}
+	t = p.next()
+	op := Op(strings.ToLower(t.text))
+	if _, known := sqlOps[op]; t.kind != tokenIdent || (!known && op != Like) {
+		return nil, fmt.Errorf("unknown operator %s at position %d, allowed operators are eq,ne,gt,ge,lt,le,like", t, t.pos)
+	}
+	if op == Like && f.Kind != String {
+		return nil, fmt.Errorf("operator like is not supported on field %q at position %d", f.Name, t.pos)
+	}
+	t = p.next()
+	v, err := value(f, t)
+	if err != nil {
+		return nil, err
+	}
+	return &Comparison{Field: f, Op: op, Value: v}, nil
+}
+
+// value converts the literal token t to the Go value of the field f
+func value(f *Field, t token) (interface{}, error) {
+	switch f.Kind {
+	case Number:
+		if t.kind == tokenNumber {
+			if n, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+				return n, nil
+			}
+		}
+		return nil, fmt.Errorf("expecting an integer value for field %q but got %s at position %d", f.Name, t, t.pos)
+	case Time:
+		if t.kind == tokenString {
+			if ts, err := ParseTime(t.text); err == nil {
+				return ts, nil
+			}
+		}
+		return nil, fmt.Errorf("expecting an RFC 3339 timestamp or date value for field %q but got %s at position %d", f.Name, t, t.pos)
+	default:
+		if t.kind == tokenString {
+			return t.text, nil
+		}
+		return nil, fmt.Errorf("expecting a quoted string value for field %q but got %s at position %d", f.Name, t, t.pos)
+	}
+}
+
+// ParseTime parses the RFC 3339 timestamp or the date s
+func ParseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
@@ -0,0 +1,175 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptrace/bun"
+)
+
+type testModel struct {
+	ID        int       `bun:",pk" query:"filter,sort"`
+	Name      string    `bun:",notnull" query:"filter,sort"`
+	Season    string    `query:"filter"`
+	CreatedAt time.Time `bun:"created" query:"sort"`
+	Secret    string
+}
+
+var testFields = FieldsOf((*testModel)(nil))
+
+func TestFieldsOf(t *testing.T) {
+	assert.Equal(t, []string{"created", "id", "name", "season"}, testFields.Names(func(*Field) bool { return true }))
+	assert.Equal(t, []string{"id", "name", "season"}, testFields.Names(func(f *Field) bool { return f.Filterable }))
+	assert.Equal(t, []string{"created", "id", "name"}, testFields.Names(func(f *Field) bool { return f.Sortable }))
+	assert.Equal(t, Time, testFields["created"].Kind)
+	assert.Equal(t, Number, testFields["id"].Kind)
+	assert.Equal(t, "Mango", testFields["name"].Value(&testModel{Name: "Mango"}))
+}
+
+func TestParseFilter(t *testing.T) {
+	testCases := map[string]struct {
+		filter  string
+		sql     string
+		args    []interface{}
+		wantErr string
+	}{
+		"eq": {
+			filter: "season eq 'Summer'",
+			sql:    "UPPER(?) = ?",
+			args:   []interface{}{bun.Ident("season"), "SUMMER"},
+		},
+		"andLike": {
+			filter: "season eq 'Summer' and name like 'ber'",
+			sql:    "(UPPER(?) = ? AND UPPER(?) LIKE ? ESCAPE '!')",
+			args:   []interface{}{bun.Ident("season"), "SUMMER", bun.Ident("name"), "%BER%"},
+		},
+		"precedence": {
+			filter: "id gt 2 or id lt 1 AND not (name eq 'it''s')",
+			sql:    "(? > ? OR (? < ? AND NOT (UPPER(?) = ?)))",
+			args:   []interface{}{bun.Ident("id"), int64(2), bun.Ident("id"), int64(1), bun.Ident("name"), "IT'S"},
+		},
+		"likeEscape": {
+			filter: "name like '10%_!'",
+			sql:    "UPPER(?) LIKE ? ESCAPE '!'",
+			args:   []interface{}{bun.Ident("name"), "%10!%!_!!%"},
+		},
+		"unknownField": {
+			filter:  "color eq 'red'",
+			wantErr: `unknown filter field "color" at position 0, allowed fields are id,name,season`,
+		},
+		"notFilterable": {
+			filter:  "created gt '2022-01-01'",
+			wantErr: `unknown filter field "created"`,
+		},
+		"unknownOperator": {
+			filter:  "name is 'Mango'",
+			wantErr: `unknown operator "is" at position 5`,
+		},
+		"likeOnNumber": {
+			filter:  "id like '1'",
+			wantErr: `operator like is not supported on field "id"`,
+		},
+		"invalidValue": {
+			filter:  "id eq 'one'",
+			wantErr: `expecting an integer value for field "id"`,
+		},
+		"unterminatedString": {
+			filter:  "name eq 'Mango",
+			wantErr: "unterminated string at position 8",
+		},
+		"missingParen": {
+			filter:  "(name eq 'Mango'",
+			wantErr: "expecting ')' but got end of filter",
+		},
+		"trailingTokens": {
+			filter:  "name eq 'Mango' name",
+			wantErr: `unexpected "name" at position 16`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			e, err := ParseFilter(tc.filter, testFields)
+			if tc.wantErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tc.wantErr)
+				}
+				return
+			}
+			if assert.NoError(t, err) {
+				b, args := e.AppendSQL(nil, nil)
+				assert.Equal(t, tc.sql, string(b))
+				assert.Equal(t, tc.args, args)
+			}
+		})
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	s, err := ParseSort("-created, name", testFields)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "-created,name", s.String())
+		after, err := s.After([]interface{}{"2022-01-01", "Mango"})
+		if assert.NoError(t, err) {
+			b, args := after.AppendSQL(nil, nil)
+			assert.Equal(t, "(? < ? OR (? = ? AND ? > ?))", string(b))
+			assert.Equal(t, []interface{}{
+				bun.Ident("created"), "2022-01-01",
+				bun.Ident("created"), "2022-01-01",
+				bun.Ident("name"), "Mango",
+			}, args)
+		}
+	}
+
+	_, err = ParseSort("season", testFields)
+	if assert.Error(t, err) {
+		assert.Equal(t, `unknown sort field "season", allowed fields are created,id,name`, err.Error())
+	}
+}
+
+func TestMatch(t *testing.T) {
+	m := &testModel{ID: 5, Name: "Blueberry", Season: "Summer"}
+	testCases := map[string]struct {
+		filter string
+		want   bool
+	}{
+		"eqIgnoresCase":   {filter: "season eq 'summer'", want: true},
+		"ne":              {filter: "season ne 'Summer'", want: false},
+		"like":            {filter: "name like 'BER'", want: true},
+		"likeNoMatch":     {filter: "name like 'mango'", want: false},
+		"number":          {filter: "id ge 5 and id lt 6", want: true},
+		"notOr":           {filter: "not (id eq 1 or name eq 'mango')", want: true},
+		"andShortCircuit": {filter: "id gt 5 and name eq 'Blueberry'", want: false},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			e, err := ParseFilter(tc.filter, testFields)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.want, e.Match(m))
+			}
+		})
+	}
+}
+
+func TestSortLess(t *testing.T) {
+	s, err := ParseSort("-created,name", testFields)
+	if !assert.NoError(t, err) {
+		return
+	}
+	now := time.Now()
+	older := &testModel{Name: "Apple", CreatedAt: now.Add(-time.Hour)}
+	newer := &testModel{Name: "Pear", CreatedAt: now}
+	sameTime := &testModel{Name: "Banana", CreatedAt: now}
+	assert.True(t, s.Less(newer, older))
+	assert.False(t, s.Less(older, newer))
+	assert.True(t, s.Less(sameTime, newer))
+	assert.False(t, s.Less(newer, newer))
+
+	after, err := s.After(s.Values(sameTime))
+	if assert.NoError(t, err) {
+		assert.True(t, after.Match(newer))
+		assert.True(t, after.Match(older))
+		assert.False(t, after.Match(sameTime))
+	}
+}
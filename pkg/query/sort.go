@@ -0,0 +1,136 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/uptrace/bun"
+)
+
+// SortField is a field to sort on
+type SortField struct {
+	Field *Field
+	Desc  bool
+}
+
+// Sort is the ordered list of fields to sort on
+type Sort []SortField
+
+// ParseSort parses the comma separated list of fields s such as `-created_at,name`
+// into Sort. A field prefixed with '-' is sorted in descending order. Only the
+// Sortable fields could be used.
+func ParseSort(s string, fields Fields) (Sort, error) {
+	var sort Sort
+	if strings.TrimSpace(s) == "" {
+		return sort, nil
+	}
+	for _, n := range strings.Split(s, ",") {
+		n = strings.TrimSpace(n)
+		sf := SortField{}
+		if strings.HasPrefix(n, "-") {
+			sf.Desc = true
+			n = n[1:]
+		} else {
+			n = strings.TrimPrefix(n, "+")
+		}
+		f, ok := fields[n]
+		if !ok || !f.Sortable {
+			return nil, fmt.Errorf("unknown sort field %q, allowed fields are %s",
+				n, strings.Join(fields.Names(func(f *Field) bool { return f.Sortable }), ","))
+		}
+		sf.Field = f
+		sort = append(sort, sf)
+	}
+	return sort, nil
+}
+
+// String returns the sort in the same format ParseSort accepts
+func (s Sort) String() string {
+	names := make([]string, 0, len(s))
+	for _, sf := range s {
+		if sf.Desc {
+			names = append(names, "-"+sf.Field.Name)
+		} else {
+			names = append(names, sf.Field.Name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// Order applies the sort to the query
+func (s Sort) Order(q *bun.SelectQuery) *bun.SelectQuery {
+	for _, sf := range s {
+		if sf.Desc {
+			q = q.OrderExpr("? DESC", bun.Ident(sf.Field.Name))
+		} else {
+			q = q.OrderExpr("? ASC", bun.Ident(sf.Field.Name))
+		}
+	}
+	return q
+}
+
+// Values returns the values of the sort fields in the model
+func (s Sort) Values(model interface{}) []interface{} {
+	values := make([]interface{}, 0, len(s))
+	for _, sf := range s {
+		values = append(values, sf.Field.Value(model))
+	}
+	return values
+}
+
+// After returns the keyset condition that matches the rows sorted after the
+// row having the values for the sort fields
+func (s Sort) After(values []interface{}) (Expr, error) {
+	if len(values) != len(s) {
+		return nil, fmt.Errorf("expecting %d sort values but got %d", len(s), len(values))
+	}
+	var after Expr
+	//(k1 > v1) OR (k1 = v1 AND k2 > v2) ... built from the last field
+	for i := len(s) - 1; i >= 0; i-- {
+		op := Gt
+		if s[i].Desc {
+			op = Lt
+		}
+		e := Expr(&keyComparison{Comparison{Field: s[i].Field, Op: op, Value: values[i]}})
+		if after != nil {
+			after = &Or{
+				Left:  e,
+				Right: &And{Left: &keyComparison{Comparison{Field: s[i].Field, Op: Eq, Value: values[i]}}, Right: after},
+			}
+		} else {
+			after = e
+		}
+	}
+	return after, nil
+}
+
+// Less reports whether the model a sorts before the model b
+func (s Sort) Less(a, b interface{}) bool {
+	for _, sf := range s {
+		c := Compare(sf.Field.Value(a), sf.Field.Value(b))
+		if c == 0 {
+			continue
+		}
+		if sf.Desc {
+			return c > 0
+		}
+		return c < 0
+	}
+	return false
+}
+
+// keyComparison is a case sensitive Comparison used for keyset pagination
+type keyComparison struct {
+	Comparison
+}
+
+// AppendSQL implements Expr
+func (e *keyComparison) AppendSQL(b []byte, args []interface{}) ([]byte, []interface{}) {
+	b = append(b, "? "+sqlOps[e.Op]+" ?"...)
+	return b, append(args, bun.Ident(e.Field.Name), e.Value)
+}
+
+// Match implements Expr
+func (e *keyComparison) Match(model interface{}) bool {
+	return compareOp(e.Op, Compare(e.Field.Value(model), e.Value))
+}
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"strings"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	//HeaderActor is the request header naming who makes the change, it is recorded in the fruit history
+	HeaderActor = "X-Actor"
+	//AnonymousActor is the actor of the requests without the HeaderActor
+	AnonymousActor = "anonymous"
+)
+
+// Actor creates the middleware that sets the actor of the request context from
+// the HeaderActor request header, the changes made by the request are recorded
+// with it in the fruit history
+func Actor() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			actor := strings.TrimSpace(c.Request().Header.Get(HeaderActor))
+			if actor == "" {
+				actor = AnonymousActor
+			}
+			ctx := db.WithActor(c.Request().Context(), actor)
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// errBackupsDisabled answers the backup requests when there is no backup dir
+var errBackupsDisabled = apperrors.BadRequest(errors.New("the backups are disabled, set the backup.dir"))
+
+// Backups are the admin endpoints backing up the sqlite database, they are
+// served on the admin listener along with the metrics
+type Backups struct {
+	DB *db.Config
+	// Dir is the directory of the backups, empty disables them
+	Dir db.BackupDir
+	// Compress gzips the backups whose requests don't tell otherwise
+	Compress bool
+	Log      *logrus.Logger
+}
+
+// NewBackups creates the Backups of the database dbc to the dir
+func NewBackups(dbc *db.Config, dir db.BackupDir, compress bool, log *logrus.Logger) *Backups {
+	return &Backups{
+		DB:       dbc,
+		Dir:      dir,
+		Compress: compress,
+		Log:      log,
+	}
+}
+
+// CreateBackup takes a backup of the database while it is served, the
+// compress query parameter tells whether it is gzipped. It answers 201
+// Created with the db.BackupInfo of the backup.
+func (b *Backups) CreateBackup(c echo.Context) error {
+	log := utils.LogEntry(c.Request().Context(), b.Log)
+	if b.Dir == "" {
+		return errBackupsDisabled
+	}
+	compress := b.Compress
+	if err := echo.QueryParamsBinder(c).
+		Bool("compress", &compress).
+		BindError(); err != nil {
+		return err
+	}
+	info, err := b.Dir.Backup(c.Request().Context(), b.DB, compress)
+	if err != nil {
+		log.Errorf("Error backing up the database, %v", err)
+		return err
+	}
+	log.Infof("Backed up the database to %s", info.Name)
+	return c.JSON(http.StatusCreated, info)
+}
+
+// ListBackups lists the db.BackupInfo of the backups, the newest first
+func (b *Backups) ListBackups(c echo.Context) error {
+	if b.Dir == "" {
+		return errBackupsDisabled
+	}
+	backups, err := b.Dir.List()
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, backups)
+}
+
+// DownloadBackup answers the backup named by the name path parameter as an attachment
+func (b *Backups) DownloadBackup(c echo.Context) error {
+	if b.Dir == "" {
+		return errBackupsDisabled
+	}
+	name := c.Param("name")
+	file, err := b.Dir.File(name)
+	if err != nil {
+		return err
+	}
+	return c.Attachment(file, name)
+}
@@ -0,0 +1,102 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackups(t *testing.T) {
+	if utils.LookupEnvOrString("FRUITS_DB_TYPE", "sqlite") != "sqlite" {
+		t.Skip("the backups are only supported by the sqlite database")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dbc, err := loadFixtures(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := db.BackupDir(t.TempDir())
+	serve := func(b *Backups, method, target string) *httptest.ResponseRecorder {
+		e := newEcho()
+		e.POST("/backups", b.CreateBackup)
+		e.GET("/backups", b.ListBackups)
+		e.GET("/backups/:name", b.DownloadBackup)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	testCases := map[string]struct {
+		dir            db.BackupDir
+		target         string
+		wantCompressed bool
+		statusCode     int
+	}{
+		"compressed": {
+			dir:            dir,
+			target:         "/backups",
+			wantCompressed: true,
+			statusCode:     http.StatusCreated,
+		},
+		"notCompressed": {
+			dir:        dir,
+			target:     "/backups?compress=false",
+			statusCode: http.StatusCreated,
+		},
+		"badCompress": {
+			dir:        dir,
+			target:     "/backups?compress=maybe",
+			statusCode: http.StatusBadRequest,
+		},
+		"disabled": {
+			target:     "/backups",
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			b := NewBackups(dbc, tc.dir, true, log)
+			rec := serve(b, http.MethodPost, tc.target)
+			if !assert.Equal(t, tc.statusCode, rec.Code, rec.Body.String()) || rec.Code != http.StatusCreated {
+				return
+			}
+			var info db.BackupInfo
+			if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantCompressed, info.Compressed)
+			assert.Positive(t, info.Size)
+
+			rec = serve(b, http.MethodGet, "/backups/"+info.Name)
+			if assert.Equal(t, http.StatusOK, rec.Code) {
+				assert.Equal(t, fmt.Sprintf("attachment; filename=%q", info.Name), rec.Header().Get(echo.HeaderContentDisposition))
+				assert.Equal(t, info.Size, int64(rec.Body.Len()))
+			}
+		})
+	}
+
+	b := NewBackups(dbc, dir, true, log)
+	rec := serve(b, http.MethodGet, "/backups")
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		var backups []db.BackupInfo
+		if err := json.Unmarshal(rec.Body.Bytes(), &backups); err != nil {
+			t.Fatal(err)
+		}
+		if assert.Len(t, backups, 2) {
+			assert.False(t, backups[0].Compressed, "Expecting the newest backup first")
+		}
+	}
+	rec = serve(b, http.MethodGet, "/backups/fruits-20230115T093000.000Z.db")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
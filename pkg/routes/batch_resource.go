@@ -0,0 +1,183 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	//MaxBatchSize is the maximum number of operations of a batch request
+	MaxBatchSize = 1000
+	//BatchAtomic applies either all the operations of the batch or none of them
+	BatchAtomic = "atomic"
+	//BatchBestEffort applies each operation of the batch on its own
+	BatchBestEffort = "best-effort"
+)
+
+// BatchResult is the outcome of one operation of a batch request
+type BatchResult struct {
+	// Index is the position of the operation in the request
+	Index int `json:"index"`
+	// Op is the operation
+	Op db.BatchOp `json:"op"`
+	// Status is the HTTP status code the operation would have got as a single request,
+	// 424 Failed Dependency when it was rolled back because another operation failed
+	Status int `json:"status"`
+	// Fruit is the inserted or upserted fruit
+	Fruit *db.Fruit `json:"fruit,omitempty"`
+	// Error is the reason the operation failed
+	Error *utils.Problem `json:"error,omitempty"`
+}
+
+// BatchResponse is the result of a batch request
+type BatchResponse struct {
+	Mode      string        `json:"mode"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// BatchFruits godoc
+// @Summary Insert, upsert and delete fruits in one request
+// @Description Applies a list of operations. An insert adds the fruit, an upsert updates the fruit having
+// @Description the same name ignoring the case or adds it when there is none and a delete moves the fruit
+// @Description with the id to the trash. In the atomic mode either all the operations are applied or none
+// @Description of them, in the best-effort mode each operation is applied on its own.
+// @Tags fruit
+// @Accept json
+// @Produce json
+// @Param mode query string false "How to apply the operations" Enums(atomic, best-effort) default(atomic)
+// @Param message body []db.BatchOperation true "Operations to apply"
+// @Success 200 {object} BatchResponse "All the operations succeeded"
+// @Success 207 {object} BatchResponse "Some of the operations failed, see the status of each result"
+// @Failure 400 {object} utils.Problem
+// @Failure 500 {object} utils.Problem
+// @Failure 504 {object} utils.Problem
+// @Router /fruits/batch [post]
+func (e *Endpoints) BatchFruits(c echo.Context) error {
+	log := utils.LogEntry(c.Request().Context(), e.Log)
+	ctx := c.Request().Context()
+	mode := BatchAtomic
+	if err := echo.QueryParamsBinder(c).
+		String("mode", &mode).
+		BindError(); err != nil {
+		return err
+	}
+	if mode != BatchAtomic && mode != BatchBestEffort {
+		return apperrors.BadRequest(fmt.Errorf("unknown batch mode %q, allowed modes are %s,%s", mode, BatchAtomic, BatchBestEffort))
+	}
+	var ops []db.BatchOperation
+	if err := c.Bind(&ops); err != nil {
+		return err
+	}
+	if len(ops) == 0 || len(ops) > MaxBatchSize {
+		return apperrors.BadRequest(fmt.Errorf("a batch must have between 1 and %d operations", MaxBatchSize))
+	}
+
+	//the invalid operations are not sent to the repository, in the atomic
+	//mode they make the whole batch fail
+	results := make([]db.BatchResult, len(ops))
+	var valid []db.BatchOperation
+	var validIndex []int
+	for i, op := range ops {
+		if err := validateBatchOp(c, op); err != nil {
+			results[i].Err = err
+			continue
+		}
+		valid = append(valid, op)
+		validIndex = append(validIndex, i)
+	}
+	atomic := mode == BatchAtomic
+	switch {
+	case atomic && len(valid) < len(ops):
+		for _, i := range validIndex {
+			results[i].Err = db.ErrBatchRolledBack
+		}
+	case len(valid) > 0:
+		log.Infof("Applying %d batch operations in %s mode", len(valid), mode)
+		applied, err := e.Repo.Batch(ctx, valid, atomic)
+		if err != nil {
+			log.Errorf("Error applying the batch operations, %v", err)
+			return err
+		}
+		for j, i := range validIndex {
+			results[i] = applied[j]
+		}
+	}
+
+	res := &BatchResponse{
+		Mode:    mode,
+		Results: make([]BatchResult, len(ops)),
+	}
+	for i, r := range results {
+		res.Results[i] = batchResult(i, ops[i].Op, r)
+		if r.Err != nil {
+			res.Failed++
+			if res.Results[i].Status >= http.StatusInternalServerError {
+				log.Errorf("Error applying batch operation %d, %v", i, r.Err)
+			}
+		} else {
+			res.Succeeded++
+		}
+	}
+	log.Infof("Batch of %d operations applied, %d succeeded and %d failed", len(ops), res.Succeeded, res.Failed)
+	if res.Failed > 0 {
+		return c.JSON(http.StatusMultiStatus, res)
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// validateBatchOp checks the operation has what it needs to be applied
+func validateBatchOp(c echo.Context, op db.BatchOperation) error {
+	switch op.Op {
+	case db.BatchInsert, db.BatchUpsert:
+		if op.Fruit == nil {
+			return apperrors.Validation(apperrors.FieldError{
+				Field:   "fruit",
+				Rule:    "required",
+				Message: fmt.Sprintf("fruit is required to %s", op.Op),
+			})
+		}
+		return c.Validate(op.Fruit)
+	case db.BatchDelete:
+		if op.ID <= 0 {
+			return apperrors.Validation(apperrors.FieldError{
+				Field:   "id",
+				Rule:    "required",
+				Message: "id is required to delete",
+			})
+		}
+	}
+	//the repository rejects the unknown operations
+	return nil
+}
+
+// batchResult maps the result of the operation i to its response
+func batchResult(i int, op db.BatchOp, r db.BatchResult) BatchResult {
+	res := BatchResult{
+		Index: i,
+		Op:    op,
+		Fruit: r.Fruit,
+	}
+	switch {
+	case errors.Is(r.Err, db.ErrBatchRolledBack):
+		res.Status = http.StatusFailedDependency
+		res.Error = newProblem(ProblemBatchRolledBack, res.Status, r.Err.Error())
+	case r.Err != nil:
+		res.Error = toProblem(r.Err)
+		res.Status = res.Error.Status
+	case op == db.BatchDelete:
+		res.Status = http.StatusNoContent
+	case r.Created:
+		res.Status = http.StatusCreated
+	default:
+		res.Status = http.StatusOK
+	}
+	return res
+}
@@ -0,0 +1,127 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchFruits(t *testing.T) {
+	fixtureNames := []string{"Apple Fall", "Banana Summer", "Blueberry Summer", "Lemon Winter", "Mango Spring", "Orange Winter", "Pear Fall", "Strawberry Spring", "Watermelon Summer"}
+	testCases := map[string]struct {
+		mode         string
+		requestBody  string
+		statusCode   int
+		wantStatuses []int
+		wantNames    []string
+	}{
+		"atomic": {
+			requestBody: `[
+				{"op":"insert","fruit":{"name":"Kiwi","season":"Winter"}},
+				{"op":"upsert","fruit":{"name":"apple","season":"Winter"}},
+				{"op":"upsert","fruit":{"name":"Papaya","season":"Summer"}},
+				{"op":"delete","id":9}
+			]`,
+			statusCode:   http.StatusOK,
+			wantStatuses: []int{http.StatusCreated, http.StatusOK, http.StatusCreated, http.StatusNoContent},
+			wantNames:    []string{"Banana Summer", "Blueberry Summer", "Kiwi Winter", "Lemon Winter", "Mango Spring", "Orange Winter", "Papaya Summer", "Strawberry Spring", "Watermelon Summer", "apple Winter"},
+		},
+		"atomicInvalid": {
+			requestBody: `[
+				{"op":"insert","fruit":{"name":"Kiwi","season":"Winter"}},
+				{"op":"insert","fruit":{"name":"Fig","season":"Monsoon"}},
+				{"op":"delete"}
+			]`,
+			statusCode:   http.StatusMultiStatus,
+			wantStatuses: []int{http.StatusFailedDependency, http.StatusUnprocessableEntity, http.StatusUnprocessableEntity},
+			wantNames:    fixtureNames,
+		},
+		"atomicFailure": {
+			requestBody: `[
+				{"op":"insert","fruit":{"name":"Kiwi","season":"Winter"}},
+				{"op":"replace","fruit":{"name":"Fig","season":"Fall"}}
+			]`,
+			statusCode:   http.StatusMultiStatus,
+			wantStatuses: []int{http.StatusFailedDependency, http.StatusBadRequest},
+			wantNames:    fixtureNames,
+		},
+		"bestEffort": {
+			mode: BatchBestEffort,
+			requestBody: `[
+				{"op":"insert","fruit":{"name":"Kiwi","season":"Winter"}},
+				{"op":"insert","fruit":{"name":"Fig","season":"Monsoon"}},
+				{"op":"delete","id":8}
+			]`,
+			statusCode:   http.StatusMultiStatus,
+			wantStatuses: []int{http.StatusCreated, http.StatusUnprocessableEntity, http.StatusNoContent},
+			wantNames:    []string{"Banana Summer", "Blueberry Summer", "Kiwi Winter", "Lemon Winter", "Mango Spring", "Orange Winter", "Pear Fall", "Strawberry Spring", "Watermelon Summer"},
+		},
+		"unknownMode": {
+			mode:        "eventually",
+			requestBody: `[{"op":"delete","id":8}]`,
+			statusCode:  http.StatusBadRequest,
+		},
+		"empty": {
+			requestBody: `[]`,
+			statusCode:  http.StatusBadRequest,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			dbc, err := loadFixtures(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e := newEcho()
+			target := "/api/fruits/batch"
+			if tc.mode != "" {
+				target += "?mode=" + tc.mode
+			}
+			req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			repo := db.NewBunFruitRepository(dbc)
+			handle(e, c, NewEndpoints(repo, log).BatchFruits)
+			if !assert.Equal(t, tc.statusCode, rec.Code, rec.Body.String()) || tc.wantStatuses == nil {
+				return
+			}
+			var got BatchResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			var statuses []int
+			for i, r := range got.Results {
+				assert.Equal(t, i, r.Index)
+				statuses = append(statuses, r.Status)
+				if r.Status >= http.StatusBadRequest {
+					assert.NotNil(t, r.Error, "Expecting result %d to have an error", i)
+				}
+			}
+			assert.Equal(t, tc.wantStatuses, statuses)
+			assert.Equal(t, len(tc.wantStatuses), got.Succeeded+got.Failed)
+
+			fruits, _, err := repo.List(ctx, db.ListOptions{})
+			if assert.NoError(t, err) {
+				var names []string
+				for _, f := range fruits {
+					names = append(names, f.Name+" "+f.Season)
+				}
+				sort.Strings(names)
+				assert.Equal(t, tc.wantNames, names)
+			}
+		})
+	}
+}
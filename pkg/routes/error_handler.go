@@ -0,0 +1,119 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// ProblemTypeBase is the base URI of the problem types, each type is
+// documented in docs/problems.md
+const ProblemTypeBase = "https://github.com/kameshsampath/go-fruits-api/blob/main/docs/problems.md#"
+
+// Problem types
+const (
+	ProblemNotFound        = ProblemTypeBase + "not-found"
+	ProblemConflict        = ProblemTypeBase + "conflict"
+	ProblemPrecondition    = ProblemTypeBase + "precondition-failed"
+	ProblemValidation      = ProblemTypeBase + "validation"
+	ProblemBadRequest      = ProblemTypeBase + "bad-request"
+	ProblemUnavailable     = ProblemTypeBase + "unavailable"
+	ProblemTimeout         = ProblemTypeBase + "timeout"
+	ProblemBatchRolledBack = ProblemTypeBase + "batch-rolled-back"
+	ProblemInternal        = ProblemTypeBase + "internal"
+	ProblemHTTP            = "about:blank"
+	internalErrorDetail    = "the server encountered an unexpected error"
+)
+
+// NewHTTPErrorHandler creates the echo.HTTPErrorHandler that writes the errors
+// returned by the handlers as application/problem+json responses
+func NewHTTPErrorHandler(log *logrus.Logger) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		if c.Response().Committed {
+			return
+		}
+		log := utils.LogEntry(c.Request().Context(), log)
+		p := toProblem(err)
+		//drivers do not always return the context error when the
+		//request deadline interrupts the query
+		if ctxErr := c.Request().Context().Err(); errors.Is(ctxErr, context.DeadlineExceeded) && p.Status == http.StatusInternalServerError {
+			p = toProblem(ctxErr)
+		}
+		p.Instance = c.Request().URL.Path
+		if p.Status >= http.StatusInternalServerError {
+			log.Errorf("Error handling %s %s, %v", c.Request().Method, c.Request().URL, err)
+		}
+		var werr error
+		if c.Request().Method == http.MethodHead {
+			werr = c.NoContent(p.Status)
+		} else {
+			c.Response().Header().Set(echo.HeaderContentType, utils.MIMEApplicationProblemJSON)
+			werr = c.JSON(p.Status, p)
+		}
+		if werr != nil {
+			log.Errorf("Error writing the problem response, %v", werr)
+		}
+	}
+}
+
+// toProblem maps the err to its problem details
+func toProblem(err error) *utils.Problem {
+	var (
+		notFound     *apperrors.NotFoundError
+		conflict     *apperrors.ConflictError
+		precondition *apperrors.PreconditionFailedError
+		validation   *apperrors.ValidationError
+		badRequest   *apperrors.BadRequestError
+		unavailable  *apperrors.UnavailableError
+		bindingErr   *echo.BindingError
+		httpErr      *echo.HTTPError
+		netErr       net.Error
+	)
+	switch {
+	case errors.As(err, &notFound):
+		return newProblem(ProblemNotFound, http.StatusNotFound, notFound.Error())
+	case errors.As(err, &conflict):
+		p := newProblem(ProblemConflict, http.StatusConflict, conflict.Error())
+		p.ExistingID = conflict.ExistingID
+		return p
+	case errors.As(err, &precondition):
+		return newProblem(ProblemPrecondition, http.StatusPreconditionFailed, precondition.Error())
+	case errors.As(err, &validation):
+		p := newProblem(ProblemValidation, http.StatusUnprocessableEntity, "the request payload has invalid fields")
+		p.Errors = validation.Errors
+		return p
+	case errors.As(err, &badRequest):
+		return newProblem(ProblemBadRequest, http.StatusBadRequest, badRequest.Error())
+	case errors.As(err, &unavailable), errors.Is(err, context.Canceled):
+		return newProblem(ProblemUnavailable, http.StatusServiceUnavailable, "the service is temporarily unavailable, retry later")
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return newProblem(ProblemTimeout, http.StatusGatewayTimeout, "the database did not answer in time, retry later")
+	case errors.As(err, &bindingErr):
+		//the BindingError unwraps to its cause instead of its HTTPError
+		return newProblem(ProblemBadRequest, bindingErr.Code, fmt.Sprintf("%v, field=%s", bindingErr.Message, bindingErr.Field))
+	case errors.As(err, &httpErr):
+		detail := fmt.Sprintf("%v", httpErr.Message)
+		if httpErr.Internal != nil && httpErr.Code < http.StatusInternalServerError {
+			detail = fmt.Sprintf("%s, %v", detail, httpErr.Internal)
+		}
+		return newProblem(ProblemHTTP, httpErr.Code, detail)
+	default:
+		return newProblem(ProblemInternal, http.StatusInternalServerError, internalErrorDetail)
+	}
+}
+
+func newProblem(typ string, status int, detail string) *utils.Problem {
+	return &utils.Problem{
+		Type:   typ,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
@@ -0,0 +1,177 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	testCases := map[string]struct {
+		err  error
+		want utils.Problem
+	}{
+		"notFound": {
+			err: apperrors.NotFound("fruit", 5),
+			want: utils.Problem{
+				Type:   ProblemNotFound,
+				Title:  "Not Found",
+				Status: http.StatusNotFound,
+				Detail: "fruit with id 5 not found",
+			},
+		},
+		"wrappedConflict": {
+			err: fmt.Errorf("saving fruit, %w", apperrors.Conflict("fruit %q already exists", "Mango")),
+			want: utils.Problem{
+				Type:   ProblemConflict,
+				Title:  "Conflict",
+				Status: http.StatusConflict,
+				Detail: `fruit "Mango" already exists`,
+			},
+		},
+		"alreadyExists": {
+			err: apperrors.AlreadyExists("fruit", "Mango", 1),
+			want: utils.Problem{
+				Type:       ProblemConflict,
+				Title:      "Conflict",
+				Status:     http.StatusConflict,
+				Detail:     `fruit "Mango" already exists with id 1`,
+				ExistingID: float64(1),
+			},
+		},
+		"badRequest": {
+			err: apperrors.BadRequest(errors.New("limit must be between 1 and 1000")),
+			want: utils.Problem{
+				Type:   ProblemBadRequest,
+				Title:  "Bad Request",
+				Status: http.StatusBadRequest,
+				Detail: "limit must be between 1 and 1000",
+			},
+		},
+		"unavailable": {
+			err: apperrors.Unavailable(errors.New("connection refused")),
+			want: utils.Problem{
+				Type:   ProblemUnavailable,
+				Title:  "Service Unavailable",
+				Status: http.StatusServiceUnavailable,
+				Detail: "the service is temporarily unavailable, retry later",
+			},
+		},
+		"deadline": {
+			err: fmt.Errorf("listing fruits, %w", context.DeadlineExceeded),
+			want: utils.Problem{
+				Type:   ProblemTimeout,
+				Title:  "Gateway Timeout",
+				Status: http.StatusGatewayTimeout,
+				Detail: "the database did not answer in time, retry later",
+			},
+		},
+		"canceled": {
+			err: context.Canceled,
+			want: utils.Problem{
+				Type:   ProblemUnavailable,
+				Title:  "Service Unavailable",
+				Status: http.StatusServiceUnavailable,
+				Detail: "the service is temporarily unavailable, retry later",
+			},
+		},
+		"echoError": {
+			err: echo.NewHTTPError(http.StatusBadRequest, "failed to bind").SetInternal(errors.New("bad id")),
+			want: utils.Problem{
+				Type:   ProblemHTTP,
+				Title:  "Bad Request",
+				Status: http.StatusBadRequest,
+				Detail: "failed to bind, bad id",
+			},
+		},
+		"bindingError": {
+			err: echo.NewBindingError("limit", []string{"many"}, "failed to bind field value to int", errors.New("invalid syntax")),
+			want: utils.Problem{
+				Type:   ProblemBadRequest,
+				Title:  "Bad Request",
+				Status: http.StatusBadRequest,
+				Detail: "failed to bind field value to int, field=limit",
+			},
+		},
+		"internal": {
+			err: errors.New("pq: relation fruits does not exist"),
+			want: utils.Problem{
+				Type:   ProblemInternal,
+				Title:  "Internal Server Error",
+				Status: http.StatusInternalServerError,
+				Detail: internalErrorDetail,
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			e := newEcho()
+			req := httptest.NewRequest(http.MethodGet, "/api/fruits/5", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			e.HTTPErrorHandler(tc.err, c)
+			assert.Equal(t, tc.want.Status, rec.Code)
+			assert.Equal(t, utils.MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+			var got utils.Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			tc.want.Instance = "/api/fruits/5"
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestHTTPErrorHandlerNonNumericID(t *testing.T) {
+	e := newEcho()
+	ep := NewEndpoints(db.NewMemoryFruitRepository(), log)
+	e.GET("/api/fruits/:id", ep.GetFruit)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/fruits/abc", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var got utils.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ProblemBadRequest, got.Type)
+	assert.Equal(t, "failed to bind field value to int, field=id", got.Detail)
+}
+
+func TestRequestTimeout(t *testing.T) {
+	e := newEcho()
+	req := httptest.NewRequest(http.MethodGet, "/api/fruits/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	h := RequestTimeout(10 * time.Millisecond)(func(c echo.Context) error {
+		<-c.Request().Context().Done()
+		//like sqlite that reports its own error when interrupted
+		return errors.New("interrupted (9)")
+	})
+	handle(e, c, h)
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	var got utils.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ProblemTimeout, got.Type)
+
+	//errors returned before the deadline are not reported as timeouts
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+	handle(e, c, RequestTimeout(time.Minute)(func(c echo.Context) error {
+		return errors.New("boom")
+	}))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
@@ -0,0 +1,79 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	//HeaderETag is the response header that holds the version of the fruit
+	HeaderETag = "ETag"
+	//HeaderIfMatch is the request header to change a fruit only when it has the given version
+	HeaderIfMatch = "If-Match"
+	//HeaderIfNoneMatch is the request header to get a fruit only when it does not have the given version
+	HeaderIfNoneMatch = "If-None-Match"
+)
+
+// etag is the strong entity tag of the fruit, it changes with the fruit version
+func etag(f *db.Fruit) string {
+	return strconv.Quote(strconv.Itoa(f.Version))
+}
+
+// setETag sets the ETag response header of the fruit
+func setETag(c echo.Context, f *db.Fruit) {
+	c.Response().Header().Set(HeaderETag, etag(f))
+}
+
+// checkIfMatch returns apperrors.PreconditionFailedError when the request has
+// an If-Match header that does not match the current version of the fruit
+func checkIfMatch(c echo.Context, f *db.Fruit) error {
+	ifMatch := c.Request().Header.Get(HeaderIfMatch)
+	if ifMatch == "" {
+		return nil
+	}
+	//If-Match uses the strong comparison, a weak tag never matches
+	if matchETag(ifMatch, etag(f), false) {
+		return nil
+	}
+	return apperrors.PreconditionFailed("fruit", f.ID)
+}
+
+// notModified reports whether the request has an If-None-Match header that
+// matches the current version of the fruit
+func notModified(c echo.Context, f *db.Fruit) bool {
+	ifNoneMatch := c.Request().Header.Get(HeaderIfNoneMatch)
+	return ifNoneMatch != "" && matchETag(ifNoneMatch, etag(f), true)
+}
+
+// matchETag reports whether the list of entity tags in the header matches the
+// tag, weak compares the tags ignoring their W/ prefix as defined by RFC 7232
+func matchETag(header, tag string, weak bool) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" {
+			return true
+		}
+		if weak {
+			t = strings.TrimPrefix(t, "W/")
+		}
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeFruit writes the fruit along with its ETag, the response is 304 Not Modified
+// when the request If-None-Match header already matches the fruit
+func writeFruit(c echo.Context, code int, f *db.Fruit) error {
+	setETag(c, f)
+	if code == http.StatusOK && c.Request().Method == http.MethodGet && notModified(c, f) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.JSON(code, f)
+}
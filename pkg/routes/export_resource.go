@@ -0,0 +1,75 @@
+package routes
+
+import (
+	"fmt"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"github.com/kameshsampath/go-fruits-api/pkg/transfer"
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/uptrace/bun/schema"
+)
+
+// ExportFruits godoc
+// @Summary Export the fruits as CSV, NDJSON, YAML or SQL
+// @Description Writes the fruits that are not in the trash ordered by their ids, each fruit is written as it is
+// @Description read from the database. The CSV and NDJSON exports could be imported back, the YAML has the
+// @Description dbfixture layout of the data.yaml and the SQL has an INSERT statement per fruit keeping its id.
+// @Tags fruit
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/yaml
+// @Produce application/sql
+// @Param format query string false "The format of the export" Enums(csv, ndjson, yaml, sql) default(csv)
+// @Param dialect query string false "The database the SQL statements are written for, the one of the API when it is not set" Enums(sqlite, pgsql, mysql)
+// @Success 200 {string} string "The fruits"
+// @Header 200 {string} Content-Disposition "The file name of the export"
+// @Failure 400 {object} utils.Problem
+// @Failure 500 {object} utils.Problem
+// @Failure 504 {object} utils.Problem
+// @Router /fruits/export [get]
+func (e *Endpoints) ExportFruits(c echo.Context) error {
+	log := utils.LogEntry(c.Request().Context(), e.Log)
+	ctx := c.Request().Context()
+	format, dialect := string(transfer.FormatCSV), e.SQLDialect
+	if err := echo.QueryParamsBinder(c).
+		String("format", &format).
+		String("dialect", &dialect).
+		BindError(); err != nil {
+		return err
+	}
+	f, err := transfer.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	var sqlDialect schema.Dialect
+	if f == transfer.FormatSQL {
+		if sqlDialect, err = transfer.SQLDialect(dialect); err != nil {
+			return err
+		}
+	}
+
+	//the response is committed by the first write, an error before it is
+	//still answered with a problem
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, f.ContentType())
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", "fruits"+f.Extension()))
+	w, err := transfer.NewWriter(f, res, sqlDialect)
+	if err != nil {
+		return err
+	}
+	n := 0
+	err = e.Repo.Export(ctx, func(f *db.Fruit) error {
+		n++
+		return w.Write(f)
+	})
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		log.Errorf("Error exporting the fruits after %d of them, %v", n, err)
+		return err
+	}
+	log.Infof("Exported %d fruits as %s", n, f)
+	return nil
+}
@@ -0,0 +1,128 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"github.com/kameshsampath/go-fruits-api/pkg/transfer"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportFruits(t *testing.T) {
+	testCases := map[string]struct {
+		query           string
+		statusCode      int
+		wantContentType string
+		wantFileName    string
+		wantLines       int
+		wantContains    string
+		wantFormat      transfer.Format
+	}{
+		"csvDefault": {
+			statusCode:      http.StatusOK,
+			wantContentType: "text/csv",
+			wantFileName:    "fruits.csv",
+			wantLines:       10,
+			wantContains:    "id,name,season,emoji,created_at,modified_at\n",
+			wantFormat:      transfer.FormatCSV,
+		},
+		"ndjson": {
+			query:           "?format=ndjson",
+			statusCode:      http.StatusOK,
+			wantContentType: "application/x-ndjson",
+			wantFileName:    "fruits.ndjson",
+			wantLines:       9,
+			wantContains:    `"name":"Apple","season":"Fall"`,
+			wantFormat:      transfer.FormatNDJSON,
+		},
+		"yaml": {
+			query:           "?format=yaml",
+			statusCode:      http.StatusOK,
+			wantContentType: "application/yaml",
+			wantFileName:    "fruits.yaml",
+			wantContains:    "- model: Fruit\n  rows:\n",
+			wantFormat:      transfer.FormatYAML,
+		},
+		"sqlDialect": {
+			query:           "?format=sql&dialect=pgsql",
+			statusCode:      http.StatusOK,
+			wantContentType: "application/sql",
+			wantFileName:    "fruits.sql",
+			wantLines:       10,
+			wantContains:    "SELECT setval(pg_get_serial_sequence('fruits', 'id')",
+		},
+		"sqlDefaultDialect": {
+			query:           "?format=sql",
+			statusCode:      http.StatusOK,
+			wantContentType: "application/sql",
+			wantFileName:    "fruits.sql",
+			wantLines:       9,
+			wantContains:    `INSERT INTO "fruits" ("id", "name", "season", "emoji", "created_at", "modified_at") VALUES (`,
+		},
+		"unknownFormat": {
+			query:      "?format=xml",
+			statusCode: http.StatusBadRequest,
+		},
+		"unknownDialect": {
+			query:      "?format=sql&dialect=oracle",
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			dbc, err := loadFixtures(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e := newEcho()
+			req := httptest.NewRequest(http.MethodGet, "/api/fruits/export"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			endpoints := NewEndpoints(db.NewBunFruitRepository(dbc), log)
+			endpoints.SQLDialect = "sqlite"
+			handle(e, c, endpoints.ExportFruits)
+			if !assert.Equal(t, tc.statusCode, rec.Code, rec.Body.String()) || tc.statusCode != http.StatusOK {
+				return
+			}
+			assert.Equal(t, tc.wantContentType, rec.Header().Get(echo.HeaderContentType))
+			assert.Equal(t, `attachment; filename="`+tc.wantFileName+`"`, rec.Header().Get(echo.HeaderContentDisposition))
+			body := rec.Body.String()
+			assert.Contains(t, body, tc.wantContains)
+			if tc.wantLines > 0 {
+				assert.Equal(t, tc.wantLines, strings.Count(body, "\n"))
+			}
+			if tc.wantFormat == "" {
+				return
+			}
+
+			//the export is imported back as the fixtures were loaded
+			rd, err := transfer.NewReader(tc.wantFormat, strings.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for {
+				row, err := rd.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if !assert.NoError(t, err) || !assert.NoError(t, row.Err) {
+					return
+				}
+				names = append(names, row.Fruit.Name+" "+row.Fruit.Season)
+			}
+			assert.Equal(t, []string{"Mango Spring", "Strawberry Spring", "Orange Winter", "Lemon Winter", "Blueberry Summer", "Banana Summer", "Watermelon Summer", "Apple Fall", "Pear Fall"}, names, "Expecting the fruits ordered by the id")
+		})
+	}
+}
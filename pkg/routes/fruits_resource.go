@@ -1,16 +1,15 @@
 package routes
 
 import (
-	"context"
-	"database/sql"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"strings"
 
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
 	"github.com/kameshsampath/go-fruits-api/pkg/db"
 	"github.com/kameshsampath/go-fruits-api/pkg/utils"
 	"github.com/labstack/echo/v4"
-	"github.com/uptrace/bun"
 )
 
 // AddFruit godoc
@@ -20,49 +19,82 @@ import (
 // @Accept json
 // @Produce json
 // @Param message body db.Fruit true "Fruit object"
-// @Success 200 {object} db.Fruit
-// @Failure 404 {object} utils.HTTPError
+// @Success 201 {object} db.Fruit
+// @Header 201 {string} Location "The URL of the added fruit"
+// @Header 201 {string} ETag "The version of the added fruit"
+// @Failure 400 {object} utils.Problem
+// @Failure 409 {object} utils.Problem
+// @Failure 422 {object} utils.Problem
+// @Failure 500 {object} utils.Problem
+// @Failure 504 {object} utils.Problem
 // @Router /fruits/add [post]
 func (e *Endpoints) AddFruit(c echo.Context) error {
-	log := e.Config.Log
-	ctx := context.Background()
-	dbConn := e.Config.DB
+	log := utils.LogEntry(c.Request().Context(), e.Log)
+	ctx := c.Request().Context()
 	f := &db.Fruit{}
 	if err := c.Bind(f); err != nil {
 		return err
 	}
+	if err := c.Validate(f); err != nil {
+		log.Errorf("Invalid fruit %v, %v", f, err)
+		return err
+	}
 	log.Infof("Adding Fruit %s", f)
-	err := dbConn.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
-		_, err := dbConn.NewInsert().
-			Model(f).
-			Exec(ctx)
-
-		if err != nil {
-			return err
-		}
-		return nil
-	})
-	if err != nil {
+	if err := e.Repo.Create(ctx, f); err != nil {
 		log.Errorf("Error adding fruit %v, %v", f, err)
-		utils.NewHTTPError(c, http.StatusInternalServerError, err)
 		return err
 	}
 	log.Infof("Fruit %s successfully saved", f)
-	return c.JSON(http.StatusCreated, f)
+	c.Response().Header().Set(echo.HeaderLocation, fmt.Sprintf("/api/fruits/%d", f.ID))
+	return writeFruit(c, http.StatusCreated, f)
+}
+
+// GetFruit godoc
+// @Summary Gets a fruit by id
+// @Description Gets the fruit with the given id
+// @Tags fruit
+// @Produce json
+// @Param id path int true "Fruit ID"
+// @Param If-None-Match header string false "ETag of the cached fruit, the fruit is only returned when it has changed"
+// @Success 200 {object} db.Fruit
+// @Header 200 {string} ETag "The version of the fruit"
+// @Success 304
+// @Failure 404 {object} utils.Problem
+// @Failure 500 {object} utils.Problem
+// @Failure 504 {object} utils.Problem
+// @Router /fruits/{id} [get]
+func (e *Endpoints) GetFruit(c echo.Context) error {
+	log := utils.LogEntry(c.Request().Context(), e.Log)
+	ctx := c.Request().Context()
+	var ID int
+	if err := echo.PathParamsBinder(c).
+		Int("id", &ID).
+		BindError(); err != nil {
+		return err
+	}
+	log.Infof("Getting Fruit with id %d", ID)
+	f, err := e.Repo.Get(ctx, ID)
+	if err != nil {
+		return err
+	}
+	return writeFruit(c, http.StatusOK, f)
 }
 
 // DeleteFruit godoc
 // @Summary Delete a fruit from Database
-// @Description Deletes a Fruit to the Database
+// @Description Moves a Fruit to the trash, it could be restored until it is purged
 // @Tags fruit
 // @Param id path int true "Fruit ID"
+// @Param If-Match header string false "ETag of the fruit, the fruit is only deleted when it has not changed since"
 // @Success 204
-// @Failure 404 {object} utils.HTTPError
+// @Failure 404 {object} utils.Problem
+// @Failure 412 {object} utils.Problem
+// @Failure 500 {object} utils.Problem
+// @Failure 504 {object} utils.Problem
 // @Router /fruits/{id} [delete]
 func (e *Endpoints) DeleteFruit(c echo.Context) error {
-	log := e.Config.Log
-	ctx := context.Background()
-	dbConn := e.Config.DB
+	log := utils.LogEntry(c.Request().Context(), e.Log)
+	ctx := c.Request().Context()
 	var ID int
 	if err := echo.PathParamsBinder(c).
 		Int("id", &ID).
@@ -70,61 +102,46 @@ func (e *Endpoints) DeleteFruit(c echo.Context) error {
 		return err
 	}
 	if ID == 0 {
-		err := fmt.Errorf("fruit with id %d not found", ID)
-		utils.NewHTTPError(c, http.StatusNotFound, err)
-		return err
+		return apperrors.NotFound("fruit", ID)
 	}
-	f := &db.Fruit{
-		ID: ID,
-	}
-	log.Infof("Deleting Fruit with id %d", ID)
-	err := dbConn.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
-		_, err := dbConn.NewDelete().
-			Model(f).
-			WherePK().
-			Exec(ctx)
+	var version int
+	if c.Request().Header.Get(HeaderIfMatch) != "" {
+		existing, err := e.Repo.Get(ctx, ID)
 		if err != nil {
 			return err
 		}
-		return nil
-	})
-	if err != nil {
+		if err := checkIfMatch(c, existing); err != nil {
+			return err
+		}
+		version = existing.Version
+	}
+	log.Infof("Deleting Fruit with id %d", ID)
+	if err := e.Repo.Delete(ctx, ID, version); err != nil {
 		log.Errorf("Error deleting fruit with ID %d, %v", ID, err)
-		utils.NewHTTPError(c, http.StatusInternalServerError, err)
 		return err
 	}
-	log.Infof("Fruit with id  %d successfully deleted", ID)
+	log.Infof("Fruit with id  %d successfully moved to the trash", ID)
 	return c.NoContent(http.StatusNoContent)
 }
 
 // DeleteAll godoc
 // @Summary Delete all fruit from Database
-// @Description Delete all fruit from Database
+// @Description Moves all the fruits to the trash, they could be restored until they are purged
 // @Tags fruit
 // @Success 204
-// @Failure 404 {object} utils.HTTPError
+// @Failure 500 {object} utils.Problem
+// @Failure 504 {object} utils.Problem
 // @Router /fruits/ [delete]
 func (e *Endpoints) DeleteAll(c echo.Context) error {
-	log := e.Config.Log
-	ctx := context.Background()
-	dbConn := e.Config.DB
+	log := utils.LogEntry(c.Request().Context(), e.Log)
+	ctx := c.Request().Context()
 
 	log.Infoln("Deleting all fruits")
-	err := dbConn.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
-		_, err := dbConn.NewTruncateTable().
-			Model((*db.Fruit)(nil)).
-			Exec(ctx)
-		if err != nil {
-			return err
-		}
-		return nil
-	})
-	if err != nil {
+	if err := e.Repo.DeleteAll(ctx); err != nil {
 		log.Errorf("Error deleting all fruits, %v", err)
-		utils.NewHTTPError(c, http.StatusInternalServerError, err)
 		return err
 	}
-	log.Infof("All fruits successfully deleted")
+	log.Infof("All fruits successfully moved to the trash")
 	return c.NoContent(http.StatusNoContent)
 }
 
@@ -134,30 +151,38 @@ func (e *Endpoints) DeleteAll(c echo.Context) error {
 // @Tags fruit
 // @Produce json
 // @Param name path string true "Full or partial name of the fruit"
+// @Param limit query int false "Maximum number of fruits to return" default(100)
+// @Param offset query int false "Number of fruits to skip"
+// @Param sort query string false "Comma separated fields to sort on, prefix a field with '-' to sort descending e.g. -created_at,name"
+// @Param cursor query string false "Opaque cursor from the X-Next-Cursor header of the previous page"
 // @Success 200 {object} db.Fruits
-// @Failure 404 {object} utils.HTTPError
+// @Header 200 {integer} X-Total-Count "Total number of matching fruits"
+// @Header 200 {string} X-Next-Cursor "Cursor to get the next page"
+// @Header 200 {string} Link "Links to the next, prev, first and last pages"
+// @Failure 400 {object} utils.Problem
+// @Failure 500 {object} utils.Problem
+// @Failure 504 {object} utils.Problem
 // @Router /fruits/search/{name} [get]
 func (e *Endpoints) GetFruitsByName(c echo.Context) error {
-	log := e.Config.Log
+	log := utils.LogEntry(c.Request().Context(), e.Log)
 	var name string
 	if err := echo.PathParamsBinder(c).
 		String("name", &name).
 		BindError(); err != nil {
-		utils.NewHTTPError(c, http.StatusInternalServerError, err)
 		return err
 	}
+	p, err := pageFromRequest(c)
+	if err != nil {
+		return apperrors.BadRequest(err)
+	}
 	log.Infof("Getting Fruit with name %s", name)
-	ctx := context.Background()
-	dbConn := e.Config.DB
-	var fruits = &db.Fruits{}
-	if err := dbConn.NewSelect().
-		Model(fruits).
-		Where(`UPPER(name) LIKE ?`, fmt.Sprintf("%%%s%%", strings.ToUpper(name))).
-		Scan(ctx); err != nil {
+	ctx := c.Request().Context()
+	fruits, total, err := e.Repo.Search(ctx, db.Criteria{Name: name}, p.listOptions(nil))
+	if err != nil {
 		log.Errorf("Error getting fruits by name %s, %v", name, err)
-		utils.NewHTTPError(c, http.StatusInternalServerError, err)
 		return err
 	}
+	p.setHeaders(c, total, fruits)
 	log.Infof("Found %d Fruits with name %s", fruits.Len(), name)
 	return c.JSON(http.StatusOK, fruits)
 }
@@ -168,30 +193,38 @@ func (e *Endpoints) GetFruitsByName(c echo.Context) error {
 // @Tags fruit
 // @Produce json
 // @Param season path string true "Full or partial name of the season"
+// @Param limit query int false "Maximum number of fruits to return" default(100)
+// @Param offset query int false "Number of fruits to skip"
+// @Param sort query string false "Comma separated fields to sort on, prefix a field with '-' to sort descending e.g. -created_at,name"
+// @Param cursor query string false "Opaque cursor from the X-Next-Cursor header of the previous page"
 // @Success 200 {object} db.Fruits
-// @Failure 404 {object} utils.HTTPError
+// @Header 200 {integer} X-Total-Count "Total number of matching fruits"
+// @Header 200 {string} X-Next-Cursor "Cursor to get the next page"
+// @Header 200 {string} Link "Links to the next, prev, first and last pages"
+// @Failure 400 {object} utils.Problem
+// @Failure 500 {object} utils.Problem
+// @Failure 504 {object} utils.Problem
 // @Router /fruits/season/{season} [get]
 func (e *Endpoints) GetFruitsBySeason(c echo.Context) error {
-	log := e.Config.Log
+	log := utils.LogEntry(c.Request().Context(), e.Log)
 	var season string
 	if err := echo.PathParamsBinder(c).
 		String("season", &season).
 		BindError(); err != nil {
-		utils.NewHTTPError(c, http.StatusInternalServerError, err)
 		return err
 	}
+	p, err := pageFromRequest(c)
+	if err != nil {
+		return apperrors.BadRequest(err)
+	}
 	log.Infof("Getting Fruit for season %s", season)
-	ctx := context.Background()
-	dbConn := e.Config.DB
-	var fruits = &db.Fruits{}
-	if err := dbConn.NewSelect().
-		Model(fruits).
-		Where("UPPER(season) = ?", strings.ToUpper(season)).
-		Scan(ctx); err != nil {
+	ctx := c.Request().Context()
+	fruits, total, err := e.Repo.Search(ctx, db.Criteria{Season: season}, p.listOptions(nil))
+	if err != nil {
 		log.Errorf("Error getting fruits for season %s, %v", season, err)
-		utils.NewHTTPError(c, http.StatusInternalServerError, err)
 		return err
 	}
+	p.setHeaders(c, total, fruits)
 	log.Infof("Found %d Fruits for season %s", fruits.Len(), season)
 	return c.JSON(http.StatusOK, fruits)
 }
@@ -201,22 +234,231 @@ func (e *Endpoints) GetFruitsBySeason(c echo.Context) error {
 // @Description Gets a list all available fruits from the database
 // @Tags fruit
 // @Produce json
+// @Param filter query string false "Filter expression e.g. season eq 'Summer' and name like 'ber'"
+// @Param limit query int false "Maximum number of fruits to return" default(100)
+// @Param offset query int false "Number of fruits to skip"
+// @Param sort query string false "Comma separated fields to sort on, prefix a field with '-' to sort descending e.g. -created_at,name"
+// @Param cursor query string false "Opaque cursor from the X-Next-Cursor header of the previous page"
 // @Success 200 {object} db.Fruits
-// @Failure 404 {object} utils.HTTPError
+// @Header 200 {integer} X-Total-Count "Total number of matching fruits"
+// @Header 200 {string} X-Next-Cursor "Cursor to get the next page"
+// @Header 200 {string} Link "Links to the next, prev, first and last pages"
+// @Failure 400 {object} utils.Problem
+// @Failure 500 {object} utils.Problem
+// @Failure 504 {object} utils.Problem
 // @Router /fruits/ [get]
 func (e *Endpoints) ListFruits(c echo.Context) error {
-	log := e.Config.Log
+	log := utils.LogEntry(c.Request().Context(), e.Log)
+	p, err := pageFromRequest(c)
+	if err != nil {
+		return apperrors.BadRequest(err)
+	}
+	filter, err := filterFromRequest(c)
+	if err != nil {
+		return apperrors.BadRequest(err)
+	}
 	log.Infoln("Getting All Fruits ")
-	ctx := context.Background()
-	dbConn := e.Config.DB
-	var fruits = &db.Fruits{}
-	if err := dbConn.NewSelect().
-		Model(fruits).
-		Scan(ctx); err != nil {
+	ctx := c.Request().Context()
+	fruits, total, err := e.Repo.List(ctx, p.listOptions(filter))
+	if err != nil {
 		log.Errorf("Error getting all fruits, %v", err)
-		utils.NewHTTPError(c, http.StatusInternalServerError, err)
 		return err
 	}
+	p.setHeaders(c, total, fruits)
 	log.Infof("Found %d Fruits", fruits.Len())
 	return c.JSON(http.StatusOK, fruits)
 }
+
+// ListTrash godoc
+// @Summary Gets the deleted fruits
+// @Description Gets a list of the fruits in the trash that could be restored
+// @Tags fruit
+// @Produce json
+// @Param filter query string false "Filter expression e.g. season eq 'Summer' and name like 'ber'"
+// @Param limit query int false "Maximum number of fruits to return" default(100)
+// @Param offset query int false "Number of fruits to skip"
+// @Param sort query string false "Comma separated fields to sort on, prefix a field with '-' to sort descending e.g. -created_at,name"
+// @Param cursor query string false "Opaque cursor from the X-Next-Cursor header of the previous page"
+// @Success 200 {object} db.Fruits
+// @Header 200 {integer} X-Total-Count "Total number of matching fruits"
+// @Header 200 {string} X-Next-Cursor "Cursor to get the next page"
+// @Header 200 {string} Link "Links to the next, prev, first and last pages"
+// @Failure 400 {object} utils.Problem
+// @Failure 500 {object} utils.Problem
+// @Failure 504 {object} utils.Problem
+// @Router /fruits/trash [get]
+func (e *Endpoints) ListTrash(c echo.Context) error {
+	log := utils.LogEntry(c.Request().Context(), e.Log)
+	p, err := pageFromRequest(c)
+	if err != nil {
+		return apperrors.BadRequest(err)
+	}
+	filter, err := filterFromRequest(c)
+	if err != nil {
+		return apperrors.BadRequest(err)
+	}
+	log.Infoln("Getting the deleted Fruits")
+	ctx := c.Request().Context()
+	fruits, total, err := e.Repo.ListDeleted(ctx, p.listOptions(filter))
+	if err != nil {
+		log.Errorf("Error getting the deleted fruits, %v", err)
+		return err
+	}
+	p.setHeaders(c, total, fruits)
+	log.Infof("Found %d deleted Fruits", fruits.Len())
+	return c.JSON(http.StatusOK, fruits)
+}
+
+// RestoreFruit godoc
+// @Summary Restore a deleted fruit
+// @Description Brings back a Fruit from the trash
+// @Tags fruit
+// @Produce json
+// @Param id path int true "Fruit ID"
+// @Success 200 {object} db.Fruit
+// @Failure 404 {object} utils.Problem
+// @Failure 409 {object} utils.Problem
+// @Failure 500 {object} utils.Problem
+// @Failure 504 {object} utils.Problem
+// @Router /fruits/{id}/restore [post]
+func (e *Endpoints) RestoreFruit(c echo.Context) error {
+	log := utils.LogEntry(c.Request().Context(), e.Log)
+	ctx := c.Request().Context()
+	var ID int
+	if err := echo.PathParamsBinder(c).
+		Int("id", &ID).
+		BindError(); err != nil {
+		return err
+	}
+	log.Infof("Restoring Fruit with id %d", ID)
+	f, err := e.Repo.Restore(ctx, ID)
+	if err != nil {
+		log.Errorf("Error restoring fruit with ID %d, %v", ID, err)
+		return err
+	}
+	log.Infof("Fruit %s successfully restored", f)
+	return writeFruit(c, http.StatusOK, f)
+}
+
+// UpdateFruit godoc
+// @Summary Update a fruit in Database
+// @Description Replaces an existing Fruit in the Database
+// @Tags fruit
+// @Accept json
+// @Produce json
+// @Param id path int true "Fruit ID"
+// @Param message body db.Fruit true "Fruit object"
+// @Param If-Match header string false "ETag of the fruit, the fruit is only changed when it has not changed since"
+// @Success 200 {object} db.Fruit
+// @Header 200 {string} ETag "The version of the changed fruit"
+// @Failure 400 {object} utils.Problem
+// @Failure 404 {object} utils.Problem
+// @Failure 409 {object} utils.Problem
+// @Failure 412 {object} utils.Problem
+// @Failure 422 {object} utils.Problem
+// @Failure 500 {object} utils.Problem
+// @Failure 504 {object} utils.Problem
+// @Router /fruits/{id} [put]
+func (e *Endpoints) UpdateFruit(c echo.Context) error {
+	log := utils.LogEntry(c.Request().Context(), e.Log)
+	ctx := c.Request().Context()
+	var ID int
+	if err := echo.PathParamsBinder(c).
+		Int("id", &ID).
+		BindError(); err != nil {
+		return err
+	}
+	existing, err := e.Repo.Get(ctx, ID)
+	if err != nil {
+		return err
+	}
+	if err := checkIfMatch(c, existing); err != nil {
+		return err
+	}
+	f := &db.Fruit{}
+	if err := c.Bind(f); err != nil {
+		return err
+	}
+	f.ID = existing.ID
+	f.CreatedAt = existing.CreatedAt
+	f.Version = existing.Version
+	if err := c.Validate(f); err != nil {
+		log.Errorf("Invalid fruit %v, %v", f, err)
+		return err
+	}
+	log.Infof("Updating Fruit %s", f)
+	if err := e.Repo.Update(ctx, f); err != nil {
+		log.Errorf("Error updating fruit %v, %v", f, err)
+		return err
+	}
+	log.Infof("Fruit %s successfully updated", f)
+	return writeFruit(c, http.StatusOK, f)
+}
+
+// PatchFruit godoc
+// @Summary Patch a fruit in Database
+// @Description Partially updates an existing Fruit in the Database using JSON Merge Patch(RFC 7386)
+// @Tags fruit
+// @Accept json
+// @Accept application/merge-patch+json
+// @Produce json
+// @Param id path int true "Fruit ID"
+// @Param message body db.Fruit true "Fruit attributes to patch"
+// @Param If-Match header string false "ETag of the fruit, the fruit is only changed when it has not changed since"
+// @Success 200 {object} db.Fruit
+// @Header 200 {string} ETag "The version of the changed fruit"
+// @Failure 400 {object} utils.Problem
+// @Failure 404 {object} utils.Problem
+// @Failure 409 {object} utils.Problem
+// @Failure 412 {object} utils.Problem
+// @Failure 422 {object} utils.Problem
+// @Failure 500 {object} utils.Problem
+// @Failure 504 {object} utils.Problem
+// @Router /fruits/{id} [patch]
+func (e *Endpoints) PatchFruit(c echo.Context) error {
+	log := utils.LogEntry(c.Request().Context(), e.Log)
+	ctx := c.Request().Context()
+	var ID int
+	if err := echo.PathParamsBinder(c).
+		Int("id", &ID).
+		BindError(); err != nil {
+		return err
+	}
+	existing, err := e.Repo.Get(ctx, ID)
+	if err != nil {
+		return err
+	}
+	if err := checkIfMatch(c, existing); err != nil {
+		return err
+	}
+	patch, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return err
+	}
+	doc, err := json.Marshal(existing)
+	if err != nil {
+		return err
+	}
+	patched, err := utils.MergePatch(doc, patch)
+	if err != nil {
+		return apperrors.BadRequest(fmt.Errorf("invalid merge patch, %w", err))
+	}
+	f := &db.Fruit{}
+	if err := json.Unmarshal(patched, f); err != nil {
+		return apperrors.BadRequest(fmt.Errorf("invalid merge patch, %w", err))
+	}
+	f.ID = existing.ID
+	f.CreatedAt = existing.CreatedAt
+	f.Version = existing.Version
+	if err := c.Validate(f); err != nil {
+		log.Errorf("Invalid fruit %v, %v", f, err)
+		return err
+	}
+	log.Infof("Patching Fruit %s", f)
+	if err := e.Repo.Update(ctx, f); err != nil {
+		log.Errorf("Error patching fruit %v, %v", f, err)
+		return err
+	}
+	log.Infof("Fruit %s successfully patched", f)
+	return writeFruit(c, http.StatusOK, f)
+}
@@ -3,9 +3,11 @@ package routes
 import (
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path"
 	"sort"
@@ -14,6 +16,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+	"github.com/kameshsampath/go-fruits-api/pkg/config"
 	"github.com/kameshsampath/go-fruits-api/pkg/db"
 	"github.com/kameshsampath/go-fruits-api/pkg/utils"
 	"github.com/labstack/echo/v4"
@@ -31,6 +35,7 @@ var (
 
 func init() {
 	os.Remove(getDBFile("test"))
+	log = utils.LogSetup(os.Stdout, utils.LookupEnvOrString("TEST_LOG_LEVEL", "info"))
 }
 
 func getDBFile(dbName string) string {
@@ -38,8 +43,25 @@ func getDBFile(dbName string) string {
 	return path.Join(cwd, "testdata", fmt.Sprintf("%s.db", dbName))
 }
 
+// newEcho creates the echo instance configured like the server
+func newEcho() *echo.Echo {
+	e := echo.New()
+	e.Validator = NewValidator()
+	e.HTTPErrorHandler = NewHTTPErrorHandler(log)
+	return e
+}
+
+// handle calls the handler h with c, a returned error is handled
+// the same way the server handles it
+func handle(e *echo.Echo, c echo.Context, h echo.HandlerFunc) error {
+	err := h(c)
+	if err != nil {
+		e.HTTPErrorHandler(err, c)
+	}
+	return err
+}
+
 func loadFixtures(ctx context.Context) (*db.Config, error) {
-	log = utils.LogSetup(os.Stdout, utils.LookupEnvOrString("TEST_LOG_LEVEL", "info"))
 	dbt := utils.LookupEnvOrString("FRUITS_DB_TYPE", "sqlite")
 	var dbc *db.Config
 	if dbt == "sqlite" {
@@ -48,13 +70,14 @@ func loadFixtures(ctx context.Context) (*db.Config, error) {
 			db.WithDBType(dbt),
 			db.WithDBFile("testdata/test.db"))
 	} else {
-		dbc = db.New(
-			db.WithLogger(log),
-			db.WithDBType(dbt))
+		//the pgsql and mysql connections are configured by the environment
+		cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), nil, os.LookupEnv)
+		if err != nil {
+			return nil, err
+		}
+		dbc = db.New(append(cfg.DBOptions(), db.WithLogger(log))...)
 	}
-	dbc.Init(ctx)
-
-	if err := dbc.DB.Ping(); err != nil {
+	if err := dbc.Init(ctx); err != nil {
 		return nil, err
 	}
 
@@ -63,6 +86,12 @@ func loadFixtures(ctx context.Context) (*db.Config, error) {
 	if err := dbfx.Load(ctx, os.DirFS("."), "testdata/fixtures.yaml"); err != nil {
 		return nil, err
 	}
+	//the fixtures start without history
+	if _, err := dbc.DB.NewTruncateTable().
+		Model((*db.FruitRevision)(nil)).
+		Exec(ctx); err != nil {
+		return nil, err
+	}
 
 	return dbc, nil
 }
@@ -106,21 +135,34 @@ func TestAddFruit(t *testing.T) {
 				Season: "Summer",
 			},
 		},
+		"seasonCase": {
+			requestBody: `{
+        "id": 12,
+        "name": "Test Fruit 3",
+        "season": "wINTER"
+        }`,
+			statusCode: http.StatusCreated,
+			want: db.Fruit{
+				ID:     12,
+				Name:   "Test Fruit 3",
+				Season: "Winter",
+			},
+		},
 	}
 
-	for name, tc := range testCases {
+	//withoutId runs first as it expects the next id of the fixtures
+	for _, name := range []string{"withoutId", "withId", "seasonCase"} {
+		tc := testCases[name]
 		t.Run(name, func(t *testing.T) {
 			var got db.Fruit
-			e := echo.New()
+			e := newEcho()
 			req := httptest.NewRequest(http.MethodPost, "/api/fruits/add", strings.NewReader(tc.requestBody))
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 			rec := httptest.NewRecorder()
-			ep := &Endpoints{
-				Config: dbc,
-			}
+			ep := NewEndpoints(db.NewBunFruitRepository(dbc), log)
 			if c := e.NewContext(req, rec); assert.NoError(t, ep.AddFruit(c)) {
 				assert.Equal(t, tc.statusCode, rec.Code)
-				dbConn := ep.Config.DB
+				dbConn := dbc.DB
 				err := dbConn.NewSelect().
 					Model(&got).
 					Where("name = ?", tc.want.Name).
@@ -128,8 +170,9 @@ func TestAddFruit(t *testing.T) {
 				if err != nil {
 					t.Fatal(err)
 				}
+				assert.Equal(t, fmt.Sprintf("/api/fruits/%d", got.ID), rec.Header().Get(echo.HeaderLocation))
 				//Verify Fruit
-				if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreFields(db.Fruit{}, "CreatedAt", "ModifiedAt")); diff != "" {
+				if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreFields(db.Fruit{}, "CreatedAt", "ModifiedAt", "Version")); diff != "" {
 					t.Errorf("AddFruit() mismatch (-want +got):\n%s", diff)
 				}
 			}
@@ -137,6 +180,158 @@ func TestAddFruit(t *testing.T) {
 	}
 }
 
+func TestAddFruitDuplicateName(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dbc, err := loadFixtures(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := newEcho()
+	req := httptest.NewRequest(http.MethodPost, "/api/fruits/add", strings.NewReader(`{"name": "MANGO", "season": "Summer"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	ep := NewEndpoints(db.NewBunFruitRepository(dbc), log)
+	c := e.NewContext(req, rec)
+	assert.Error(t, handle(e, c, ep.AddFruit))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	var got utils.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ProblemConflict, got.Type)
+	assert.Equal(t, `fruit "Mango" already exists with id 1`, got.Detail)
+	//the JSON numbers are decoded as float64
+	assert.Equal(t, float64(1), got.ExistingID)
+}
+
+func TestAddFruitValidation(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dbc, err := loadFixtures(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		requestBody string
+		want        []apperrors.FieldError
+	}{
+		"missingName": {
+			requestBody: `{"season": "Spring"}`,
+			want: []apperrors.FieldError{
+				{Field: "name", Rule: "required", Message: "name is required"},
+			},
+		},
+		"longName": {
+			requestBody: fmt.Sprintf(`{"name": "%s", "season": "Spring"}`, strings.Repeat("x", 65)),
+			want: []apperrors.FieldError{
+				{Field: "name", Rule: "max", Param: "64", Message: "name must be at most 64 characters long"},
+			},
+		},
+		"unknownSeasonAndEmoji": {
+			requestBody: `{"name": "Kiwi", "season": "Monsoon", "emoji": "kiwi"}`,
+			want: []apperrors.FieldError{
+				{Field: "season", Rule: "season", Message: "season must be one of Spring,Summer,Fall,Winter"},
+				{Field: "emoji", Rule: "emoji", Message: "emoji must be a unicode codepoint like U+1F34E"},
+			},
+		},
+		"invalidCodepoint": {
+			requestBody: `{"name": "Kiwi", "season": "summer", "emoji": "U+D800"}`,
+			want: []apperrors.FieldError{
+				{Field: "emoji", Rule: "emoji", Message: "emoji must be a unicode codepoint like U+1F34E"},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			e := newEcho()
+			req := httptest.NewRequest(http.MethodPost, "/api/fruits/add", strings.NewReader(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			ep := NewEndpoints(db.NewBunFruitRepository(dbc), log)
+			c := e.NewContext(req, rec)
+			assert.Error(t, handle(e, c, ep.AddFruit))
+			assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+			assert.Equal(t, utils.MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+			var got utils.Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, ProblemValidation, got.Type)
+			assert.Equal(t, http.StatusUnprocessableEntity, got.Status)
+			assert.Equal(t, "/api/fruits/add", got.Instance)
+			assert.Equal(t, tc.want, got.Errors)
+		})
+	}
+}
+
+func TestGetFruit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dbc, err := loadFixtures(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		fruitID    string
+		statusCode int
+		want       db.Fruit
+	}{
+		"found": {
+			fruitID:    "8",
+			statusCode: http.StatusOK,
+			want: db.Fruit{
+				ID:     8,
+				Name:   "Apple",
+				Emoji:  "U+1F34E",
+				Season: "Fall",
+			},
+		},
+		"notFound": {
+			fruitID:    "999",
+			statusCode: http.StatusNotFound,
+		},
+		"zeroId": {
+			fruitID:    "0",
+			statusCode: http.StatusNotFound,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			e := newEcho()
+			req := httptest.NewRequest(http.MethodGet, "/api/fruits/:id", nil)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/fruits/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.fruitID)
+			ep := NewEndpoints(db.NewBunFruitRepository(dbc), log)
+			err := handle(e, c, ep.GetFruit)
+			assert.Equal(t, tc.statusCode, rec.Code)
+			if tc.statusCode != http.StatusOK {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				var got db.Fruit
+				if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+					t.Fatal(err)
+				}
+				//Verify Fruit
+				if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreFields(db.Fruit{}, "CreatedAt", "ModifiedAt", "Version")); diff != "" {
+					t.Errorf("GetFruit() mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
 func TestDeleteAllFruit(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -144,17 +339,15 @@ func TestDeleteAllFruit(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	e := echo.New()
+	e := newEcho()
 	req := httptest.NewRequest(http.MethodDelete, "/api/fruits/", nil)
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	ep := &Endpoints{
-		Config: dbc,
-	}
+	ep := NewEndpoints(db.NewBunFruitRepository(dbc), log)
 	if assert.NoError(t, ep.DeleteAll(c)) {
 		assert.Equal(t, http.StatusNoContent, rec.Code)
-		dbConn := ep.Config.DB
+		dbConn := dbc.DB
 		c, err := dbConn.NewSelect().
 			Model((*db.Fruit)(nil)).
 			Count(context.Background())
@@ -174,7 +367,7 @@ func TestDeleteFruit(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	e := echo.New()
+	e := newEcho()
 	req := httptest.NewRequest(http.MethodDelete, "/api/fruits/:id", nil)
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
@@ -182,12 +375,10 @@ func TestDeleteFruit(t *testing.T) {
 	c.SetPath("/api/fruits/:id")
 	c.SetParamNames("id")
 	c.SetParamValues(fruitID)
-	ep := &Endpoints{
-		Config: dbc,
-	}
+	ep := NewEndpoints(db.NewBunFruitRepository(dbc), log)
 	if assert.NoError(t, ep.DeleteFruit(c)) {
 		assert.Equal(t, http.StatusNoContent, rec.Code)
-		dbConn := ep.Config.DB
+		dbConn := dbc.DB
 		ID, _ := strconv.Atoi(fruitID)
 		exists, err := dbConn.NewSelect().
 			Model(&db.Fruit{ID: ID}).
@@ -200,6 +391,114 @@ func TestDeleteFruit(t *testing.T) {
 	}
 }
 
+func TestTrashAndRestore(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dbc, err := loadFixtures(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := newEcho()
+	ep := NewEndpoints(db.NewBunFruitRepository(dbc), log)
+	call := func(method, target string, h echo.HandlerFunc, id string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(method, target, nil), rec)
+		if id != "" {
+			c.SetParamNames("id")
+			c.SetParamValues(id)
+		}
+		handle(e, c, h)
+		return rec
+	}
+	trash := func() db.Fruits {
+		rec := call(http.MethodGet, "/api/fruits/trash?sort=id", ep.ListTrash, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var fruits db.Fruits
+		if err := json.Unmarshal(rec.Body.Bytes(), &fruits); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, strconv.Itoa(len(fruits)), rec.Header().Get(HeaderTotalCount))
+		return fruits
+	}
+
+	assert.Empty(t, trash())
+	assert.Equal(t, http.StatusNoContent, call(http.MethodDelete, "/api/fruits/5", ep.DeleteFruit, "5").Code)
+	assert.Equal(t, http.StatusNotFound, call(http.MethodGet, "/api/fruits/5", ep.GetFruit, "5").Code)
+	if deleted := trash(); assert.Len(t, deleted, 1) {
+		assert.Equal(t, 5, deleted[0].ID)
+		assert.NotNil(t, deleted[0].DeletedAt)
+	}
+
+	rec := call(http.MethodPost, "/api/fruits/5/restore", ep.RestoreFruit, "5")
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		var got db.Fruit
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "Blueberry", got.Name)
+		assert.Nil(t, got.DeletedAt)
+	}
+	assert.Equal(t, http.StatusNotFound, call(http.MethodPost, "/api/fruits/5/restore", ep.RestoreFruit, "5").Code)
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/api/fruits/5", ep.GetFruit, "5").Code)
+	assert.Empty(t, trash())
+
+	//deleting all the fruits keeps them in the trash
+	assert.Equal(t, http.StatusNoContent, call(http.MethodDelete, "/api/fruits/", ep.DeleteAll, "").Code)
+	assert.Len(t, trash(), 9)
+}
+
+func TestConditionalRequests(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dbc, err := loadFixtures(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := newEcho()
+	ep := NewEndpoints(db.NewBunFruitRepository(dbc), log)
+	call := func(method string, h echo.HandlerFunc, header, value, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/fruits/5", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("5")
+		handle(e, c, h)
+		return rec
+	}
+	blueberry := `{"name":"Blueberry","season":"Summer"}`
+
+	rec := call(http.MethodGet, ep.GetFruit, "", "", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"1"`, rec.Header().Get(HeaderETag))
+
+	rec = call(http.MethodGet, ep.GetFruit, HeaderIfNoneMatch, `"1"`, "")
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	assert.Equal(t, http.StatusNotModified, call(http.MethodGet, ep.GetFruit, HeaderIfNoneMatch, `"7", W/"1"`, "").Code)
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, ep.GetFruit, HeaderIfNoneMatch, `"2"`, "").Code)
+
+	assert.Equal(t, http.StatusPreconditionFailed, call(http.MethodPut, ep.UpdateFruit, HeaderIfMatch, `"2"`, blueberry).Code)
+	assert.Equal(t, http.StatusPreconditionFailed, call(http.MethodPut, ep.UpdateFruit, HeaderIfMatch, `W/"1"`, blueberry).Code)
+	rec = call(http.MethodPut, ep.UpdateFruit, HeaderIfMatch, `"1"`, blueberry)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get(HeaderETag))
+
+	//the version changed with the update
+	rec = call(http.MethodPatch, ep.PatchFruit, HeaderIfMatch, `"1"`, `{"season":"Fall"}`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	assert.Equal(t, utils.MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+	rec = call(http.MethodPatch, ep.PatchFruit, HeaderIfMatch, "*", `{"season":"Fall"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"3"`, rec.Header().Get(HeaderETag))
+
+	assert.Equal(t, http.StatusPreconditionFailed, call(http.MethodDelete, ep.DeleteFruit, HeaderIfMatch, `"2"`, "").Code)
+	assert.Equal(t, http.StatusNoContent, call(http.MethodDelete, ep.DeleteFruit, HeaderIfMatch, `"3"`, "").Code)
+}
+
 func TestGetFruitByName(t *testing.T) {
 	testCases := map[string]struct {
 		name string
@@ -258,7 +557,7 @@ func TestGetFruitByName(t *testing.T) {
 	}
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
-			e := echo.New()
+			e := newEcho()
 			req := httptest.NewRequest(http.MethodGet, "/api/fruits/:name", nil)
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 			rec := httptest.NewRecorder()
@@ -266,9 +565,7 @@ func TestGetFruitByName(t *testing.T) {
 			c.SetPath("/api/fruits/:name")
 			c.SetParamNames("name")
 			c.SetParamValues(tc.name)
-			ep := &Endpoints{
-				Config: dbc,
-			}
+			ep := NewEndpoints(db.NewBunFruitRepository(dbc), log)
 			if assert.NoError(t, ep.GetFruitsByName(c)) {
 				assert.Equal(t, http.StatusOK, rec.Code)
 				var got db.Fruits
@@ -279,7 +576,7 @@ func TestGetFruitByName(t *testing.T) {
 				}
 				assert.NotNil(t, got, "Expecting the response to have Fruit(s) object but got none")
 				//Verify Fruit
-				if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreFields(db.Fruit{}, "CreatedAt", "ModifiedAt")); diff != "" {
+				if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreFields(db.Fruit{}, "CreatedAt", "ModifiedAt", "Version")); diff != "" {
 					t.Errorf("GetFruitsByName() mismatch (-want +got):\n%s", diff)
 				}
 			}
@@ -393,7 +690,7 @@ func TestGetFruitsBySeason(t *testing.T) {
 	}
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
-			e := echo.New()
+			e := newEcho()
 			req := httptest.NewRequest(http.MethodGet, "/api/fruits/:season", nil)
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 			rec := httptest.NewRecorder()
@@ -401,9 +698,7 @@ func TestGetFruitsBySeason(t *testing.T) {
 			c.SetPath("/api/fruits/:season")
 			c.SetParamNames("season")
 			c.SetParamValues(tc.season)
-			ep := &Endpoints{
-				Config: dbc,
-			}
+			ep := NewEndpoints(db.NewBunFruitRepository(dbc), log)
 			if assert.NoError(t, ep.GetFruitsBySeason(c)) {
 				assert.Equal(t, http.StatusOK, rec.Code)
 				var got db.Fruits
@@ -414,7 +709,7 @@ func TestGetFruitsBySeason(t *testing.T) {
 				}
 				assert.NotNil(t, got, "Expecting the response to have Fruits object but got none")
 				//Verify Fruit
-				if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreFields(db.Fruit{}, "CreatedAt", "ModifiedAt")); diff != "" {
+				if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreFields(db.Fruit{}, "CreatedAt", "ModifiedAt", "Version")); diff != "" {
 					t.Errorf("GetFruitsBySeason() mismatch (-want +got):\n%s", diff)
 				}
 			}
@@ -429,13 +724,11 @@ func TestGetAllFruits(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	e := echo.New()
+	e := newEcho()
 	req := httptest.NewRequest(http.MethodGet, "/api/fruits", nil)
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
-	ep := &Endpoints{
-		Config: dbc,
-	}
+	ep := NewEndpoints(db.NewBunFruitRepository(dbc), log)
 	var want db.Fruits
 	cwd, _ := os.Getwd()
 	bw, err := os.ReadFile(path.Join(cwd, "testdata", "list.json"))
@@ -458,8 +751,385 @@ func TestGetAllFruits(t *testing.T) {
 		assert.NotNil(t, got, "Expecting the response to have Fruits object but got none")
 		sort.Sort(got)
 		//Verify Fruits
-		if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(db.Fruit{}, "CreatedAt", "ModifiedAt")); diff != "" {
+		if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(db.Fruit{}, "CreatedAt", "ModifiedAt", "Version")); diff != "" {
 			t.Errorf("GetAllFruits() mismatch (-want +got):\n%s", diff)
 		}
 	}
 }
+
+func TestUpdateFruit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dbc, err := loadFixtures(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		fruitID     string
+		requestBody string
+		statusCode  int
+		want        db.Fruit
+	}{
+		"update": {
+			fruitID: "9",
+			requestBody: `{
+        "name": "Pear",
+        "season": "Winter",
+        "emoji": "U+1F350"
+        }`,
+			statusCode: http.StatusOK,
+			want: db.Fruit{
+				ID:     9,
+				Name:   "Pear",
+				Season: "Winter",
+				Emoji:  "U+1F350",
+			},
+		},
+		"ignoresBodyId": {
+			fruitID: "4",
+			requestBody: `{
+        "id": 99,
+        "name": "Lime",
+        "season": "Summer"
+        }`,
+			statusCode: http.StatusOK,
+			want: db.Fruit{
+				ID:     4,
+				Name:   "Lime",
+				Season: "Summer",
+			},
+		},
+		"invalid": {
+			fruitID: "4",
+			requestBody: `{
+        "name": "",
+        "season": "Summer"
+        }`,
+			statusCode: http.StatusUnprocessableEntity,
+		},
+		"notFound": {
+			fruitID: "999",
+			requestBody: `{
+        "name": "Test Fruit",
+        "season": "Summer"
+        }`,
+			statusCode: http.StatusNotFound,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			e := newEcho()
+			req := httptest.NewRequest(http.MethodPut, "/api/fruits/:id", strings.NewReader(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/fruits/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.fruitID)
+			ep := NewEndpoints(db.NewBunFruitRepository(dbc), log)
+			err := handle(e, c, ep.UpdateFruit)
+			assert.Equal(t, tc.statusCode, rec.Code)
+			if tc.statusCode != http.StatusOK {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				got := db.Fruit{ID: tc.want.ID}
+				err := dbc.DB.NewSelect().
+					Model(&got).
+					WherePK().
+					Scan(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				//Verify Fruit
+				if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreFields(db.Fruit{}, "CreatedAt", "ModifiedAt", "Version")); diff != "" {
+					t.Errorf("UpdateFruit() mismatch (-want +got):\n%s", diff)
+				}
+				assert.False(t, got.ModifiedAt.IsZero(), "Expecting ModifiedAt to be set")
+			}
+		})
+	}
+}
+
+func TestPatchFruit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dbc, err := loadFixtures(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		fruitID     string
+		requestBody string
+		statusCode  int
+		want        db.Fruit
+	}{
+		"patchSeason": {
+			fruitID:     "8",
+			requestBody: `{"season": "Winter"}`,
+			statusCode:  http.StatusOK,
+			want: db.Fruit{
+				ID:     8,
+				Name:   "Apple",
+				Season: "Winter",
+				Emoji:  "U+1F34E",
+			},
+		},
+		"removeEmoji": {
+			fruitID:     "7",
+			requestBody: `{"emoji": null}`,
+			statusCode:  http.StatusOK,
+			want: db.Fruit{
+				ID:     7,
+				Name:   "Watermelon",
+				Season: "Summer",
+			},
+		},
+		"invalidSeason": {
+			fruitID:     "7",
+			requestBody: `{"season": "Monsoon"}`,
+			statusCode:  http.StatusUnprocessableEntity,
+		},
+		"invalidPatch": {
+			fruitID:     "7",
+			requestBody: `{"emoji":`,
+			statusCode:  http.StatusBadRequest,
+		},
+		"notFound": {
+			fruitID:     "999",
+			requestBody: `{"season": "Winter"}`,
+			statusCode:  http.StatusNotFound,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			e := newEcho()
+			req := httptest.NewRequest(http.MethodPatch, "/api/fruits/:id", strings.NewReader(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, "application/merge-patch+json")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/fruits/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.fruitID)
+			ep := NewEndpoints(db.NewBunFruitRepository(dbc), log)
+			err := handle(e, c, ep.PatchFruit)
+			assert.Equal(t, tc.statusCode, rec.Code)
+			if tc.statusCode != http.StatusOK {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				var got db.Fruit
+				if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+					t.Fatal(err)
+				}
+				//Verify Fruit
+				if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreFields(db.Fruit{}, "CreatedAt", "ModifiedAt", "Version")); diff != "" {
+					t.Errorf("PatchFruit() mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestListFruitsPagination(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dbc, err := loadFixtures(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		query      string
+		statusCode int
+		wantIDs    []int
+		wantLinks  []string
+	}{
+		"firstPage": {
+			query:      "limit=3",
+			statusCode: http.StatusOK,
+			wantIDs:    []int{1, 2, 3},
+			wantLinks:  []string{`rel="next"`, `rel="first"`, `rel="last"`},
+		},
+		"middlePage": {
+			query:      "limit=3&offset=3",
+			statusCode: http.StatusOK,
+			wantIDs:    []int{4, 5, 6},
+			wantLinks:  []string{`rel="next"`, `rel="prev"`, `rel="first"`, `rel="last"`},
+		},
+		"lastPage": {
+			query:      "limit=4&offset=8",
+			statusCode: http.StatusOK,
+			wantIDs:    []int{9},
+			wantLinks:  []string{`rel="prev"`, `rel="first"`, `rel="last"`},
+		},
+		"cursor": {
+			query:      "limit=2&cursor=" + (&cursor{Keys: []interface{}{6}}).encode(),
+			statusCode: http.StatusOK,
+			wantIDs:    []int{7, 8},
+			wantLinks:  []string{`rel="next"`},
+		},
+		"lastCursorPage": {
+			query:      "limit=3&cursor=" + (&cursor{Keys: []interface{}{7}}).encode(),
+			statusCode: http.StatusOK,
+			wantIDs:    []int{8, 9},
+		},
+		"invalidLimit": {
+			query:      "limit=0",
+			statusCode: http.StatusBadRequest,
+		},
+		"invalidCursor": {
+			query:      "cursor=foo",
+			statusCode: http.StatusBadRequest,
+		},
+		"cursorWithOffset": {
+			query:      "offset=2&cursor=" + (&cursor{Keys: []interface{}{6}}).encode(),
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			e := newEcho()
+			req := httptest.NewRequest(http.MethodGet, "/api/fruits/?"+tc.query, nil)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			ep := NewEndpoints(db.NewBunFruitRepository(dbc), log)
+			err := handle(e, c, ep.ListFruits)
+			assert.Equal(t, tc.statusCode, rec.Code)
+			if tc.statusCode != http.StatusOK {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				var got db.Fruits
+				if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+					t.Fatal(err)
+				}
+				gotIDs := make([]int, 0, len(got))
+				for _, f := range got {
+					gotIDs = append(gotIDs, f.ID)
+				}
+				assert.Equal(t, tc.wantIDs, gotIDs)
+				assert.Equal(t, "9", rec.Header().Get(HeaderTotalCount))
+				link := rec.Header().Get("Link")
+				for _, l := range tc.wantLinks {
+					assert.Contains(t, link, l)
+				}
+				assert.Equal(t, len(tc.wantLinks), strings.Count(link, "rel="))
+				_, ok := rec.Header()["Link"]
+				assert.Equal(t, len(tc.wantLinks) > 0, ok, "Expecting no Link header without links")
+			}
+		})
+	}
+}
+
+func TestListFruitsSortAndFilter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dbc, err := loadFixtures(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		query      string
+		statusCode int
+		wantNames  []string
+	}{
+		"sortDesc": {
+			query:      "sort=-name&limit=3",
+			statusCode: http.StatusOK,
+			wantNames:  []string{"Watermelon", "Strawberry", "Pear"},
+		},
+		"sortSeasonThenName": {
+			query:      "sort=season,-name&limit=3",
+			statusCode: http.StatusOK,
+			wantNames:  []string{"Pear", "Apple", "Strawberry"},
+		},
+		"filter": {
+			query:      url.Values{"filter": {"season eq 'summer' and name like 'ber'"}}.Encode(),
+			statusCode: http.StatusOK,
+			wantNames:  []string{"Blueberry"},
+		},
+		"filterAndSort": {
+			query:      url.Values{"filter": {"season eq 'Winter' or id le 2"}, "sort": {"name"}}.Encode(),
+			statusCode: http.StatusOK,
+			wantNames:  []string{"Lemon", "Mango", "Orange", "Strawberry"},
+		},
+		"unknownSortField": {
+			query:      "sort=color",
+			statusCode: http.StatusBadRequest,
+		},
+		"unknownFilterField": {
+			query:      url.Values{"filter": {"color eq 'red'"}}.Encode(),
+			statusCode: http.StatusBadRequest,
+		},
+		"unknownOperator": {
+			query:      url.Values{"filter": {"name contains 'ber'"}}.Encode(),
+			statusCode: http.StatusBadRequest,
+		},
+		"cursorSortMismatch": {
+			query:      "sort=name&cursor=" + (&cursor{Keys: []interface{}{6}}).encode(),
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			e := newEcho()
+			req := httptest.NewRequest(http.MethodGet, "/api/fruits/?"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			ep := NewEndpoints(db.NewBunFruitRepository(dbc), log)
+			err := handle(e, c, ep.ListFruits)
+			assert.Equal(t, tc.statusCode, rec.Code)
+			if tc.statusCode != http.StatusOK {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				var got db.Fruits
+				if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+					t.Fatal(err)
+				}
+				gotNames := make([]string, 0, len(got))
+				for _, f := range got {
+					gotNames = append(gotNames, f.Name)
+				}
+				assert.Equal(t, tc.wantNames, gotNames)
+			}
+		})
+	}
+
+	//walk all the pages using the cursor of a sorted list
+	var gotNames []string
+	next := ""
+	for i := 0; i < 5; i++ {
+		e := newEcho()
+		req := httptest.NewRequest(http.MethodGet, "/api/fruits/?sort=season,-name&limit=4&cursor="+next, nil)
+		rec := httptest.NewRecorder()
+		ep := NewEndpoints(db.NewBunFruitRepository(dbc), log)
+		if !assert.NoError(t, ep.ListFruits(e.NewContext(req, rec))) {
+			break
+		}
+		var got db.Fruits
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range got {
+			gotNames = append(gotNames, f.Name)
+		}
+		if next = rec.Header().Get(HeaderNextCursor); next == "" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"Pear", "Apple", "Strawberry", "Mango", "Watermelon", "Blueberry", "Banana", "Orange", "Lemon"}, gotNames)
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"net/http"
 
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
 	"github.com/labstack/echo/v4"
 )
 
@@ -24,13 +25,12 @@ func (e *Endpoints) Live(c echo.Context) error {
 // @Tags health
 // @Produce json
 // @Success 200 {object} string
+// @Failure 503 {object} utils.Problem
 // @Router /health/ready/ [get]
 func (e *Endpoints) Ready(c echo.Context) error {
-	var err error
-	if err = e.Config.DB.Ping(); err == nil {
-		c.JSON(http.StatusOK, "READY")
-		return nil
+	if err := e.Repo.Ping(c.Request().Context()); err != nil {
+		return apperrors.Unavailable(err)
 	}
-	c.JSON(http.StatusNotFound, "YDAER")
-	return err
+	c.JSON(http.StatusOK, "READY")
+	return nil
 }
@@ -0,0 +1,81 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// FruitHistory godoc
+// @Summary Get the history of a fruit
+// @Description Lists the changes of a Fruit oldest first, along with who made them and the fruit before and after each change
+// @Tags fruit
+// @Produce json
+// @Param id path int true "Fruit ID"
+// @Success 200 {array} db.FruitRevision
+// @Failure 404 {object} utils.Problem
+// @Failure 500 {object} utils.Problem
+// @Failure 504 {object} utils.Problem
+// @Router /fruits/{id}/history [get]
+func (e *Endpoints) FruitHistory(c echo.Context) error {
+	log := utils.LogEntry(c.Request().Context(), e.Log)
+	ctx := c.Request().Context()
+	var ID int
+	if err := echo.PathParamsBinder(c).
+		Int("id", &ID).
+		BindError(); err != nil {
+		return err
+	}
+	log.Infof("Getting the history of Fruit with id %d", ID)
+	revisions, err := e.Repo.History(ctx, ID)
+	if err != nil {
+		log.Errorf("Error getting the history of fruit with ID %d, %v", ID, err)
+		return err
+	}
+	return c.JSON(http.StatusOK, revisions)
+}
+
+// RevertFruit godoc
+// @Summary Revert a fruit to a revision
+// @Description Sets the name, season and emoji of a Fruit back to what they were after one of its revisions
+// @Tags fruit
+// @Produce json
+// @Param id path int true "Fruit ID"
+// @Param revision path int true "Revision ID"
+// @Param If-Match header string false "ETag of the fruit, the fruit is only changed when it has not changed since"
+// @Success 200 {object} db.Fruit
+// @Header 200 {string} ETag "The version of the changed fruit"
+// @Failure 400 {object} utils.Problem
+// @Failure 404 {object} utils.Problem
+// @Failure 409 {object} utils.Problem
+// @Failure 412 {object} utils.Problem
+// @Failure 500 {object} utils.Problem
+// @Failure 504 {object} utils.Problem
+// @Router /fruits/{id}/history/{revision}/revert [post]
+func (e *Endpoints) RevertFruit(c echo.Context) error {
+	log := utils.LogEntry(c.Request().Context(), e.Log)
+	ctx := c.Request().Context()
+	var ID, revision int
+	if err := echo.PathParamsBinder(c).
+		Int("id", &ID).
+		Int("revision", &revision).
+		BindError(); err != nil {
+		return err
+	}
+	existing, err := e.Repo.Get(ctx, ID)
+	if err != nil {
+		return err
+	}
+	if err := checkIfMatch(c, existing); err != nil {
+		return err
+	}
+	log.Infof("Reverting Fruit with id %d to revision %d", ID, revision)
+	f, err := e.Repo.Revert(ctx, ID, revision, existing.Version)
+	if err != nil {
+		log.Errorf("Error reverting fruit with ID %d to revision %d, %v", ID, revision, err)
+		return err
+	}
+	log.Infof("Fruit %s successfully reverted", f)
+	return writeFruit(c, http.StatusOK, f)
+}
@@ -0,0 +1,85 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFruitHistory(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dbc, err := loadFixtures(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := newEcho()
+	ep := NewEndpoints(db.NewBunFruitRepository(dbc), log)
+	fruits := e.Group("/api/fruits", Actor())
+	fruits.PUT("/:id", ep.UpdateFruit)
+	fruits.GET("/:id/history", ep.FruitHistory)
+	fruits.POST("/:id/history/:revision/revert", ep.RevertFruit)
+	serve := func(method, target, body string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	history := func(id int) db.FruitRevisions {
+		rec := serve(http.MethodGet, fmt.Sprintf("/api/fruits/%d/history", id), "", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var revisions db.FruitRevisions
+		if err := json.Unmarshal(rec.Body.Bytes(), &revisions); err != nil {
+			t.Fatal(err)
+		}
+		return revisions
+	}
+
+	assert.Empty(t, history(1), "Expecting the fixtures to have no history")
+	rec := serve(http.MethodPut, "/api/fruits/1", `{"name": "Mango", "season": "Summer"}`, http.Header{HeaderActor: {"alice"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	revisions := history(1)
+	if !assert.Len(t, revisions, 1) {
+		return
+	}
+	assert.Equal(t, db.RevisionUpdate, revisions[0].Operation)
+	assert.Equal(t, "alice", revisions[0].Actor)
+	assert.Equal(t, 2, revisions[0].Version)
+	assert.JSONEq(t, `{"id":1,"name":"Mango","season":"Spring","emoji":"U+1F96D"}`, string(revisions[0].Before))
+
+	//the update revision has the fruit as it was after the update
+	revert := fmt.Sprintf("/api/fruits/1/history/%d/revert", revisions[0].ID)
+	rec = serve(http.MethodPost, revert, "", http.Header{HeaderIfMatch: {`"1"`}})
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	rec = serve(http.MethodPut, "/api/fruits/1", `{"name": "Mango", "season": "Fall"}`, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = serve(http.MethodPost, revert, "", http.Header{HeaderIfMatch: {`"3"`}})
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		var got db.Fruit
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "Summer", got.Season)
+		assert.Equal(t, `"4"`, rec.Header().Get(HeaderETag))
+	}
+	if revisions = history(1); assert.Len(t, revisions, 3) {
+		assert.Equal(t, db.RevisionRevert, revisions[2].Operation)
+		assert.Equal(t, AnonymousActor, revisions[2].Actor)
+	}
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/fruits/42/history", "", nil).Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/api/fruits/2/history/1/revert", "", nil).Code)
+}
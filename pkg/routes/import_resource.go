@@ -0,0 +1,74 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"github.com/kameshsampath/go-fruits-api/pkg/transfer"
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// ImportFruits godoc
+// @Summary Import fruits from CSV, NDJSON or YAML
+// @Description Adds the fruits of the request body, which is read as it is received so it could be larger than
+// @Description the memory. The CSV has a header row naming its name, season and emoji columns, the NDJSON has a
+// @Description fruit object per line and the YAML has the dbfixture layout of the data.yaml. The rows having the
+// @Description name of an existing fruit are skipped unless update is set. Each row is validated, the invalid rows
+// @Description are rejected with their line numbers and the valid ones are imported. With dry_run the changes
+// @Description are reported without being made.
+// @Tags fruit
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Accept application/yaml
+// @Produce json
+// @Param format query string false "The format of the body, told by its Content-Type when it is not set" Enums(csv, ndjson, yaml)
+// @Param update query bool false "Update the fruits having the names of the rows" default(false)
+// @Param dry_run query bool false "Only report the changes the import would make" default(false)
+// @Param data body string true "The fruits to import"
+// @Success 200 {object} db.ImportResult "All the rows were imported"
+// @Success 207 {object} db.ImportResult "Some of the rows were rejected"
+// @Failure 400 {object} utils.Problem
+// @Failure 500 {object} utils.Problem
+// @Failure 504 {object} utils.Problem
+// @Router /fruits/import [post]
+func (e *Endpoints) ImportFruits(c echo.Context) error {
+	log := utils.LogEntry(c.Request().Context(), e.Log)
+	ctx := c.Request().Context()
+	var format string
+	opts := db.ImportOptions{Validator: c.Echo().Validator}
+	if err := echo.QueryParamsBinder(c).
+		String("format", &format).
+		Bool("update", &opts.Update).
+		Bool("dry_run", &opts.DryRun).
+		BindError(); err != nil {
+		return err
+	}
+	var f transfer.Format
+	var err error
+	if format != "" {
+		f, err = transfer.ParseFormat(format)
+	} else {
+		f, err = transfer.FormatOfContentType(c.Request().Header.Get(echo.HeaderContentType))
+	}
+	if err != nil {
+		return err
+	}
+	rd, err := transfer.NewReader(f, c.Request().Body)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Importing the %s fruits, update %t, dry-run %t", f, opts.Update, opts.DryRun)
+	res, err := e.Repo.Import(ctx, rd, opts)
+	if err != nil {
+		log.Errorf("Error importing the fruits, %v", err)
+		return err
+	}
+	log.Infof("Imported the fruits, %d inserted, %d updated, %d skipped, %d unchanged and %d rejected",
+		res.Inserted, res.Updated, res.Skipped, res.Unchanged, res.Rejected)
+	if res.Rejected > 0 {
+		return c.JSON(http.StatusMultiStatus, res)
+	}
+	return c.JSON(http.StatusOK, res)
+}
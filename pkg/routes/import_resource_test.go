@@ -0,0 +1,128 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportFruits(t *testing.T) {
+	fixtureNames := []string{"Apple Fall", "Banana Summer", "Blueberry Summer", "Lemon Winter", "Mango Spring", "Orange Winter", "Pear Fall", "Strawberry Spring", "Watermelon Summer"}
+	testCases := map[string]struct {
+		query          string
+		contentType    string
+		requestBody    string
+		statusCode     int
+		wantResult     *db.ImportResult
+		wantRejections []db.ImportRejection
+		wantNames      []string
+	}{
+		"csv": {
+			contentType: "text/csv",
+			requestBody: "name,season,emoji\nKiwi,Winter,U+1F95D\nFig,Monsoon,\nmango,Summer,\n",
+			statusCode:  http.StatusMultiStatus,
+			wantResult:  &db.ImportResult{Inserted: 1, Skipped: 1, Rejected: 1},
+			wantRejections: []db.ImportRejection{
+				{Line: 3, Error: "validation failed, season must be one of Spring,Summer,Fall,Winter"},
+			},
+			wantNames: []string{"Apple Fall", "Banana Summer", "Blueberry Summer", "Kiwi Winter", "Lemon Winter", "Mango Spring", "Orange Winter", "Pear Fall", "Strawberry Spring", "Watermelon Summer"},
+		},
+		"ndjsonUpdate": {
+			query:       "?update=true",
+			contentType: "application/x-ndjson",
+			requestBody: `{"name":"mango","season":"Summer"}` + "\n" + `{"name":"Kiwi","season":"Winter"}` + "\n",
+			statusCode:  http.StatusOK,
+			wantResult:  &db.ImportResult{Inserted: 1, Updated: 1},
+			wantNames:   []string{"Apple Fall", "Banana Summer", "Blueberry Summer", "Kiwi Winter", "Lemon Winter", "Orange Winter", "Pear Fall", "Strawberry Spring", "Watermelon Summer", "mango Summer"},
+		},
+		"seasonCase": {
+			query:       "?update=true",
+			contentType: "text/csv",
+			requestBody: "name,season,emoji\nPear,FALL,U+1F350\nKiwi,winter,\n",
+			statusCode:  http.StatusOK,
+			wantResult:  &db.ImportResult{Inserted: 1, Unchanged: 1},
+			wantNames:   []string{"Apple Fall", "Banana Summer", "Blueberry Summer", "Kiwi Winter", "Lemon Winter", "Mango Spring", "Orange Winter", "Pear Fall", "Strawberry Spring", "Watermelon Summer"},
+		},
+		"yamlFormatDryRun": {
+			query:       "?format=yaml&dry_run=true&update=true",
+			contentType: echo.MIMETextPlain,
+			requestBody: "- model: Fruit\n  rows:\n    - name: Kiwi\n      season: Winter\n    - name: Apple\n      season: Fall\n      emoji: U+1F34E\n",
+			statusCode:  http.StatusOK,
+			wantResult:  &db.ImportResult{DryRun: true, Inserted: 1, Unchanged: 1},
+			wantNames:   fixtureNames,
+		},
+		"unknownContentType": {
+			contentType: echo.MIMEApplicationJSON,
+			requestBody: `[{"name":"Kiwi","season":"Winter"}]`,
+			statusCode:  http.StatusBadRequest,
+			wantNames:   fixtureNames,
+		},
+		"unknownFormat": {
+			query:       "?format=xml",
+			contentType: "text/csv",
+			requestBody: "name,season\nKiwi,Winter\n",
+			statusCode:  http.StatusBadRequest,
+			wantNames:   fixtureNames,
+		},
+		"invalidHeader": {
+			contentType: "text/csv",
+			requestBody: "fruit,season\nKiwi,Winter\n",
+			statusCode:  http.StatusBadRequest,
+			wantNames:   fixtureNames,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			dbc, err := loadFixtures(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e := newEcho()
+			req := httptest.NewRequest(http.MethodPost, "/api/fruits/import"+tc.query, strings.NewReader(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, tc.contentType)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			repo := db.NewBunFruitRepository(dbc)
+			handle(e, c, NewEndpoints(repo, log).ImportFruits)
+			if assert.Equal(t, tc.statusCode, rec.Code, rec.Body.String()) && tc.wantResult != nil {
+				var got db.ImportResult
+				if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, tc.wantResult.Inserted, got.Inserted)
+				assert.Equal(t, tc.wantResult.Updated, got.Updated)
+				assert.Equal(t, tc.wantResult.Skipped, got.Skipped)
+				assert.Equal(t, tc.wantResult.Unchanged, got.Unchanged)
+				assert.Equal(t, tc.wantResult.Rejected, got.Rejected)
+				assert.Equal(t, tc.wantResult.DryRun, got.DryRun)
+				assert.Len(t, got.Changes, got.Inserted+got.Updated+got.Skipped)
+				if tc.wantRejections == nil {
+					tc.wantRejections = []db.ImportRejection{}
+				}
+				assert.Equal(t, tc.wantRejections, got.Rejections)
+			}
+
+			fruits, _, err := repo.List(ctx, db.ListOptions{})
+			if assert.NoError(t, err) {
+				var names []string
+				for _, f := range fruits {
+					names = append(names, f.Name+" "+f.Season)
+				}
+				sort.Strings(names)
+				assert.Equal(t, tc.wantNames, names)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package routes
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/sirupsen/logrus"
+)
+
+// maxRequestIDLength is the length of the longest X-Request-ID propagated from
+// the requests, the longer ones are replaced by a generated id
+const maxRequestIDLength = 128
+
+// RequestID creates the middleware that propagates the X-Request-ID request header,
+// or generates one when the request has none, to the response and the request
+// context so that the logs of the request are tagged with it
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id := req.Header.Get(echo.HeaderXRequestID)
+			if !validRequestID(id) {
+				id = newRequestID()
+				req.Header.Set(echo.HeaderXRequestID, id)
+			}
+			c.Response().Header().Set(echo.HeaderXRequestID, id)
+			c.SetRequest(req.WithContext(utils.WithRequestID(req.Context(), id)))
+			return next(c)
+		}
+	}
+}
+
+// validRequestID reports whether the id could be propagated, it must be made of
+// printable ASCII characters so that it is safe to log
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	//crypto/rand never fails on the supported platforms
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// AccessLog creates the middleware that logs each request once it is handled,
+// along with its id, method, URI, status, latency, remote IP and the bytes
+// received and sent. The errors of the handlers are handled by the middleware
+// so that the logged status is the one of the error response.
+func AccessLog(log *logrus.Logger) echo.MiddlewareFunc {
+	logger := middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
+		LogLatency:      true,
+		LogRemoteIP:     true,
+		LogMethod:       true,
+		LogURI:          true,
+		LogRequestID:    true,
+		LogUserAgent:    true,
+		LogStatus:       true,
+		LogResponseSize: true,
+		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
+			entry := utils.LogEntry(c.Request().Context(), log).WithFields(logrus.Fields{
+				utils.FieldRequestID: v.RequestID,
+				"method":             v.Method,
+				"uri":                v.URI,
+				"status":             v.Status,
+				"latency_ms":         float64(v.Latency) / float64(time.Millisecond),
+				"remote_ip":          v.RemoteIP,
+				"user_agent":         v.UserAgent,
+				"bytes_in":           c.Request().ContentLength,
+				"bytes_out":          v.ResponseSize,
+			})
+			if v.Status >= http.StatusInternalServerError {
+				entry.Error("request")
+			} else {
+				entry.Info("request")
+			}
+			return nil
+		},
+	})
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return logger(func(c echo.Context) error {
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+			return nil
+		})
+	}
+}
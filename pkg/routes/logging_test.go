@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestLogging(t *testing.T) {
+	testCases := map[string]struct {
+		requestID string
+		handler   echo.HandlerFunc
+		status    int
+		generated bool
+	}{
+		"generated": {
+			handler:   func(c echo.Context) error { return c.String(http.StatusOK, "ok") },
+			status:    http.StatusOK,
+			generated: true,
+		},
+		"propagated": {
+			requestID: "3c5e2a7b-req",
+			handler:   func(c echo.Context) error { return c.String(http.StatusOK, "ok") },
+			status:    http.StatusOK,
+		},
+		"invalid": {
+			requestID: "bad id\nlevel=error",
+			handler:   func(c echo.Context) error { return c.String(http.StatusOK, "ok") },
+			status:    http.StatusOK,
+			generated: true,
+		},
+		"error": {
+			requestID: "3c5e2a7b-err",
+			handler:   func(c echo.Context) error { return apperrors.NotFound("fruit", 42) },
+			status:    http.StatusNotFound,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			accessLog := utils.LogSetup(&out, "info")
+			e := newEcho()
+			e.Use(RequestID(), AccessLog(accessLog))
+			var handlerRequestID string
+			e.GET("/api/fruits/:id", func(c echo.Context) error {
+				handlerRequestID = utils.RequestID(c.Request().Context())
+				return tc.handler(c)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/fruits/42", nil)
+			if tc.requestID != "" {
+				req.Header.Set(echo.HeaderXRequestID, tc.requestID)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.status, rec.Code)
+			id := rec.Header().Get(echo.HeaderXRequestID)
+			if tc.generated {
+				assert.Regexp(t, "^[0-9a-f]{32}$", id)
+			} else {
+				assert.Equal(t, tc.requestID, id)
+			}
+			assert.Equal(t, id, handlerRequestID, "Expecting the request context to carry the request id")
+
+			lines := bufio.NewScanner(strings.NewReader(out.String()))
+			if !assert.True(t, lines.Scan(), "Expecting an access log line") {
+				return
+			}
+			var got map[string]interface{}
+			if err := json.Unmarshal(lines.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, id, got[utils.FieldRequestID])
+			assert.Equal(t, http.MethodGet, got["method"])
+			assert.Equal(t, "/api/fruits/42", got["uri"])
+			assert.Equal(t, float64(tc.status), got["status"])
+			assert.Equal(t, float64(rec.Body.Len()), got["bytes_out"])
+			assert.Contains(t, got, "latency_ms")
+			assert.Contains(t, got, "remote_ip")
+			assert.False(t, lines.Scan(), "Expecting a single log line")
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package routes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"github.com/kameshsampath/go-fruits-api/pkg/query"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	//DefaultPageLimit is the number of fruits returned when the request has no limit
+	DefaultPageLimit = 100
+	//MaxPageLimit is the maximum number of fruits that could be returned in one page
+	MaxPageLimit = 1000
+	//HeaderTotalCount is the response header that holds the total number of matching fruits
+	HeaderTotalCount = "X-Total-Count"
+	//HeaderNextCursor is the response header that holds the cursor of the next page
+	HeaderNextCursor = "X-Next-Cursor"
+)
+
+// fruitFields are the db.Fruit fields that could be used to filter and sort
+var fruitFields = db.FruitFields()
+
+// page holds the pagination and sort parameters of a list request
+type page struct {
+	Limit  int
+	Offset int
+	Sort   query.Sort
+	After  *cursor
+}
+
+// cursor is the opaque keyset position of the last fruit of a page, Keys
+// are the values of the page keyset fields of that fruit
+type cursor struct {
+	Sort string        `json:"sort,omitempty"`
+	Keys []interface{} `json:"keys"`
+}
+
+func (c *cursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor decodes the cursor s that was encoded for the page p
+func (p *page) decodeCursor(s string) (*cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor %q", s)
+	}
+	c := &cursor{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("invalid cursor %q", s)
+	}
+	if c.Sort != p.Sort.String() {
+		return nil, fmt.Errorf("cursor %q does not match the sort %q", s, p.Sort)
+	}
+	keyset := p.keyset()
+	if len(c.Keys) != len(keyset) {
+		return nil, fmt.Errorf("invalid cursor %q", s)
+	}
+	for i, sf := range keyset {
+		v, err := sf.Field.Convert(c.Keys[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor %q, %w", s, err)
+		}
+		c.Keys[i] = v
+	}
+	return c, nil
+}
+
+// pageFromRequest builds the page from the query parameters limit, offset, sort and cursor
+func pageFromRequest(c echo.Context) (*page, error) {
+	p := &page{
+		Limit: DefaultPageLimit,
+	}
+	var sort, after string
+	if err := echo.QueryParamsBinder(c).
+		Int("limit", &p.Limit).
+		Int("offset", &p.Offset).
+		String("sort", &sort).
+		String("cursor", &after).
+		BindError(); err != nil {
+		return nil, err
+	}
+	if p.Limit < 1 || p.Limit > MaxPageLimit {
+		return nil, fmt.Errorf("limit must be between 1 and %d", MaxPageLimit)
+	}
+	if p.Offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative")
+	}
+	var err error
+	if p.Sort, err = query.ParseSort(sort, fruitFields); err != nil {
+		return nil, err
+	}
+	if after != "" {
+		if p.Offset > 0 {
+			return nil, fmt.Errorf("cursor and offset can not be used together")
+		}
+		if p.After, err = p.decodeCursor(after); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// filterFromRequest parses the filter query parameter, the
+// filter is nil when the request has none
+func filterFromRequest(c echo.Context) (query.Expr, error) {
+	if filter := c.QueryParam("filter"); filter != "" {
+		return query.ParseFilter(filter, fruitFields)
+	}
+	return nil, nil
+}
+
+// keyset is the page sort followed by the id to make the order of the fruits stable
+func (p *page) keyset() query.Sort {
+	for _, sf := range p.Sort {
+		if sf.Field.Name == "id" {
+			return p.Sort
+		}
+	}
+	return append(p.Sort[:len(p.Sort):len(p.Sort)], query.SortField{Field: fruitFields["id"]})
+}
+
+// listOptions are the repository options to list the fruits of the
+// page matching the filter
+func (p *page) listOptions(filter query.Expr) db.ListOptions {
+	opts := db.ListOptions{
+		Filter: filter,
+		Sort:   p.keyset(),
+		Limit:  p.Limit,
+		Offset: p.Offset,
+	}
+	if p.After != nil {
+		opts.After = p.After.Keys
+	}
+	return opts
+}
+
+// setHeaders sets the X-Total-Count, X-Next-Cursor and Link response headers
+// for the fruits of this page
+func (p *page) setHeaders(c echo.Context, total int, fruits db.Fruits) {
+	h := c.Response().Header()
+	h.Set(HeaderTotalCount, strconv.Itoa(total))
+
+	var next string
+	if len(fruits) == p.Limit {
+		next = (&cursor{
+			Sort: p.Sort.String(),
+			Keys: p.keyset().Values(fruits[len(fruits)-1]),
+		}).encode()
+		h.Set(HeaderNextCursor, next)
+	}
+
+	limit := strconv.Itoa(p.Limit)
+	var links []string
+	if p.After != nil {
+		if next != "" {
+			links = append(links, pageLink(c, "next", map[string]string{"limit": limit, "cursor": next}))
+		}
+	} else {
+		if p.Offset+len(fruits) < total {
+			links = append(links, pageLink(c, "next", map[string]string{"limit": limit, "offset": strconv.Itoa(p.Offset + p.Limit)}))
+		}
+		if p.Offset > 0 {
+			prev := p.Offset - p.Limit
+			if prev < 0 {
+				prev = 0
+			}
+			links = append(links, pageLink(c, "prev", map[string]string{"limit": limit, "offset": strconv.Itoa(prev)}))
+		}
+		last := 0
+		if total > 0 {
+			last = ((total - 1) / p.Limit) * p.Limit
+		}
+		links = append(links,
+			pageLink(c, "first", map[string]string{"limit": limit, "offset": "0"}),
+			pageLink(c, "last", map[string]string{"limit": limit, "offset": strconv.Itoa(last)}))
+	}
+	if len(links) > 0 {
+		h.Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// pageLink builds the Link header value of the relation rel by
+// replacing the params in the request URL
+func pageLink(c echo.Context, rel string, params map[string]string) string {
+	u := *c.Request().URL
+	q := u.Query()
+	q.Del("cursor")
+	q.Del("offset")
+	for k, v := range params {
+		q.Set(k, v)
+	}
+	u.RawQuery = q.Encode()
+	return fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), rel)
+}
@@ -0,0 +1,71 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// StatusStarting is reported by the startup probe until the database is reachable and migrated
+	StatusStarting = "starting"
+	// StatusStarted is reported by the startup probe once the application serves the API
+	StatusStarted = "started"
+)
+
+// errStarting answers the requests made while the application starts
+var errStarting = errors.New("the application is starting, the database is not reachable or migrated yet")
+
+// Startup tracks the start of the application, the API is served while the
+// database is initialized so that the startup probe could tell how far it is
+type Startup struct {
+	started atomic.Bool
+}
+
+// NewStartup creates the Startup of an application that is starting
+func NewStartup() *Startup {
+	return &Startup{}
+}
+
+// Started marks the application as started. Whatever the handlers need, like
+// the repository of the Endpoints, is to be set before it, the handlers behind
+// the Gate see it once they are let through.
+func (s *Startup) Started() {
+	s.started.Store(true)
+}
+
+// IsStarted tells whether the application started
+func (s *Startup) IsStarted() bool {
+	return s.started.Load()
+}
+
+// Gate answers the requests with 503 Service Unavailable until the application started
+func (s *Startup) Gate() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !s.IsStarted() {
+				c.Response().Header().Set(echo.HeaderRetryAfter, "1")
+				return apperrors.Unavailable(errStarting)
+			}
+			return next(c)
+		}
+	}
+}
+
+// Probe godoc
+// @Summary Checks whether the API started
+// @Description Reports "starting" with 503 until the database is reachable and migrated, "started" afterwards, can be used with Kubernetes startup Probes
+// @Tags health
+// @Produce json
+// @Success 200 {object} string
+// @Failure 503 {object} string
+// @Router /health/startup/ [get]
+func (s *Startup) Probe(c echo.Context) error {
+	if !s.IsStarted() {
+		return c.JSON(http.StatusServiceUnavailable, StatusStarting)
+	}
+	return c.JSON(http.StatusOK, StatusStarted)
+}
@@ -0,0 +1,49 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartup(t *testing.T) {
+	startup := NewStartup()
+	e := newEcho()
+	e.GET("/api/health/startup", startup.Probe)
+	e.GET("/api/fruits/", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, "fruits")
+	}, startup.Gate())
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/health/startup")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `"starting"`, rec.Body.String())
+
+	rec = get("/api/fruits/")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get(echo.HeaderRetryAfter))
+	var problem utils.Problem
+	if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem)) {
+		assert.Equal(t, http.StatusServiceUnavailable, problem.Status)
+	}
+
+	startup.Started()
+
+	rec = get("/api/health/startup")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `"started"`, rec.Body.String())
+
+	rec = get("/api/fruits/")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `"fruits"`, rec.Body.String())
+}
@@ -2,17 +2,23 @@ package routes
 
 import (
 	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"github.com/sirupsen/logrus"
 )
 
-//Endpoints is the marker interface for defining routes
+// Endpoints is the marker interface for defining routes
 type Endpoints struct {
-	Config *db.Config
+	Repo db.FruitRepository
+	Log  *logrus.Logger
+	// SQLDialect is the database type, like pgsql, the SQL exports are written
+	// for when they don't ask for one
+	SQLDialect string
 }
 
-//NewEndpoints gives handle to REST Endpoints
-//dbType could be one of "pg","mysql","sqlite".Defaults to "sqlite"
-func NewEndpoints(dbc *db.Config) *Endpoints {
+// NewEndpoints gives handle to REST Endpoints that store the fruits
+// in the repository repo
+func NewEndpoints(repo db.FruitRepository, log *logrus.Logger) *Endpoints {
 	return &Endpoints{
-		Config: dbc,
+		Repo: repo,
+		Log:  log,
 	}
 }
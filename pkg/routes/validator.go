@@ -0,0 +1,103 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"github.com/labstack/echo/v4"
+)
+
+var emojiPattern = regexp.MustCompile(`^U\+([0-9A-Fa-f]{4,6})$`)
+
+// Validator validates the request payloads using their `validate` struct tags
+type Validator struct {
+	validate *validator.Validate
+}
+
+var _ echo.Validator = (*Validator)(nil)
+
+// NewValidator creates the Validator with the fruit specific rules
+// season and emoji registered
+func NewValidator() *Validator {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(sf reflect.StructField) string {
+		if n := strings.Split(sf.Tag.Get("json"), ",")[0]; n != "" && n != "-" {
+			return n
+		}
+		return sf.Name
+	})
+	//the rules are registered on a fresh instance, the registration never fails
+	_ = v.RegisterValidation("season", validateSeason)
+	_ = v.RegisterValidation("emoji", validateEmoji)
+	return &Validator{
+		validate: v,
+	}
+}
+
+// Validate implements echo.Validator, the failed validations are returned
+// as *apperrors.ValidationError
+func (v *Validator) Validate(i interface{}) error {
+	err := v.validate.Struct(i)
+	if err == nil {
+		return nil
+	}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	fieldErrors := make([]apperrors.FieldError, 0, len(verrs))
+	for _, fe := range verrs {
+		fieldErrors = append(fieldErrors, apperrors.FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: fieldErrorMessage(fe),
+		})
+	}
+	return apperrors.Validation(fieldErrors...)
+}
+
+func fieldErrorMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", fe.Field())
+	case "max":
+		return fmt.Sprintf("%s must be at most %s characters long", fe.Field(), fe.Param())
+	case "season":
+		return fmt.Sprintf("%s must be one of %s", fe.Field(), strings.Join(db.Seasons, ","))
+	case "emoji":
+		return fmt.Sprintf("%s must be a unicode codepoint like U+1F34E", fe.Field())
+	default:
+		return fmt.Sprintf("%s failed the %s rule", fe.Field(), fe.Tag())
+	}
+}
+
+// validateSeason checks that the field is one of the db.Seasons ignoring the case,
+// the fruits are saved with the season spelled as in the db.Seasons
+func validateSeason(fl validator.FieldLevel) bool {
+	for _, s := range db.Seasons {
+		if strings.EqualFold(s, fl.Field().String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateEmoji checks that the field is a valid unicode codepoint in the
+// form U+XXXX
+func validateEmoji(fl validator.FieldLevel) bool {
+	m := emojiPattern.FindStringSubmatch(fl.Field().String())
+	if m == nil {
+		return false
+	}
+	cp, err := strconv.ParseInt(m[1], 16, 32)
+	return err == nil && utf8.ValidRune(rune(cp))
+}
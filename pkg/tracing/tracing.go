@@ -0,0 +1,111 @@
+// Package tracing sets up the OpenTelemetry tracing of the Fruits API.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+)
+
+const (
+	// ServiceName is the default name of the service reporting the spans,
+	// OTEL_SERVICE_NAME overrides it
+	ServiceName = "fruits-api"
+
+	// ExporterNone only propagates the trace context, no spans are exported
+	ExporterNone = "none"
+	// ExporterOTLP exports the spans to the OTLP/HTTP endpoint configured with
+	// the standard OTEL_EXPORTER_OTLP_* variables
+	ExporterOTLP = "otlp"
+	// ExporterStdout writes the spans as JSON to the standard output
+	ExporterStdout = "stdout"
+	// ExporterFile writes the spans as JSON to a file
+	ExporterFile = "file"
+)
+
+// Setup installs the global tracer provider exporting the spans with the exporter,
+// file is the file the ExporterFile appends the spans to. The W3C trace context
+// propagator is installed whatever the exporter. The returned function flushes
+// the pending spans and releases the exporter.
+func Setup(ctx context.Context, exporter string, file string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	var exp sdktrace.SpanExporter
+	closeFile := func() error { return nil }
+	var err error
+	switch exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exp, err = otlptracehttp.New(ctx)
+	case ExporterStdout:
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterFile:
+		if file == "" {
+			return nil, fmt.Errorf("the %s trace exporter needs a file", ExporterFile)
+		}
+		var f *os.File
+		if f, err = os.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644); err != nil {
+			return nil, err
+		}
+		closeFile = f.Close
+		exp, err = NewWriterExporter(f)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, valid exporters are %s,%s,%s,%s", exporter, ExporterNone, ExporterOTLP, ExporterStdout, ExporterFile)
+	}
+	if err != nil {
+		closeFile()
+		return nil, err
+	}
+	tp, err := NewProvider(ctx, sdktrace.WithBatcher(exp))
+	if err != nil {
+		closeFile()
+		return nil, err
+	}
+	otel.SetTracerProvider(tp)
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if cerr := closeFile(); err == nil {
+			err = cerr
+		}
+		return err
+	}, nil
+}
+
+// NewWriterExporter creates the exporter writing the spans to w as a JSON
+// object per span, it lets the traces be checked offline
+func NewWriterExporter(w io.Writer) (sdktrace.SpanExporter, error) {
+	return stdouttrace.New(stdouttrace.WithWriter(w))
+}
+
+// NewProvider creates the tracer provider of the service with the opts, the
+// resource of the spans is detected from the OTEL_* variables
+func NewProvider(ctx context.Context, opts ...sdktrace.TracerProviderOption) (*sdktrace.TracerProvider, error) {
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceNameKey.String(ServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK())
+	if err != nil {
+		return nil, err
+	}
+	return sdktrace.NewTracerProvider(append([]sdktrace.TracerProviderOption{sdktrace.WithResource(res)}, opts...)...), nil
+}
+
+// Middleware creates the middleware that starts a span per request named after
+// its route, the span continues the trace of the W3C traceparent request header
+func Middleware() echo.MiddlewareFunc {
+	return otelecho.Middleware(ServiceName)
+}
@@ -0,0 +1,157 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"github.com/kameshsampath/go-fruits-api/pkg/routes"
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// span is the part of the spans written by the writer exporter checked by the tests
+type span struct {
+	Name        string
+	SpanContext struct {
+		TraceID string
+		SpanID  string
+	}
+	Parent struct {
+		SpanID string
+	}
+	Attributes []struct {
+		Key   string
+		Value struct {
+			Value interface{}
+		}
+	}
+}
+
+func (s span) attribute(key string) interface{} {
+	for _, a := range s.Attributes {
+		if a.Key == key {
+			return a.Value.Value
+		}
+	}
+	return nil
+}
+
+func decodeSpans(t *testing.T, r io.Reader) map[string]span {
+	spans := map[string]span{}
+	dec := json.NewDecoder(r)
+	for dec.More() {
+		var s span
+		if err := dec.Decode(&s); err != nil {
+			t.Fatal(err)
+		}
+		spans[s.Name] = s
+	}
+	return spans
+}
+
+func TestMiddleware(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var traces, logs bytes.Buffer
+	exp, err := NewWriterExporter(&traces)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp, err := NewProvider(ctx, sdktrace.WithSyncer(exp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Setup(ctx, ExporterNone, ""); err != nil {
+		t.Fatal(err)
+	}
+	otel.SetTracerProvider(tp)
+	defer tp.Shutdown(ctx)
+
+	log := utils.LogSetup(&logs, "info")
+	dbc := db.New(
+		db.WithLogger(utils.LogSetup(io.Discard, "info")),
+		db.WithDBType("sqlite"),
+		db.WithDBFile(path.Join(t.TempDir(), "tracing.db")))
+	if err := dbc.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	repo := db.NewBunFruitRepository(dbc)
+	e := echo.New()
+	e.Use(routes.RequestID(), Middleware())
+	e.GET("/api/fruits/", func(c echo.Context) error {
+		fruits, _, err := repo.List(c.Request().Context(), db.ListOptions{})
+		if err != nil {
+			return err
+		}
+		utils.LogEntry(c.Request().Context(), log).Info("listed the fruits")
+		return c.JSON(http.StatusOK, fruits)
+	})
+
+	const traceID, parentID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	req := httptest.NewRequest(http.MethodGet, "/api/fruits/", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentID+"-01")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	spans := decodeSpans(t, &traces)
+	route, ok := spans["/api/fruits/"]
+	if !assert.True(t, ok, "Expecting a span named after the route but got %v", spans) {
+		return
+	}
+	assert.Equal(t, traceID, route.SpanContext.TraceID, "Expecting the trace of the traceparent header to be continued")
+	assert.Equal(t, parentID, route.Parent.SpanID)
+	query, ok := spans["SELECT"]
+	if assert.True(t, ok, "Expecting a span for the query but got %v", spans) {
+		assert.Equal(t, traceID, query.SpanContext.TraceID)
+		assert.Equal(t, route.SpanContext.SpanID, query.Parent.SpanID, "Expecting the query span to be a child of the route span")
+		assert.Contains(t, query.attribute("db.statement"), `FROM "fruits"`)
+		assert.Equal(t, "sqlite", query.attribute("db.system"))
+	}
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(logs.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, traceID, line[utils.FieldTraceID])
+	assert.Equal(t, route.SpanContext.SpanID, line[utils.FieldSpanID])
+	assert.Equal(t, rec.Header().Get(echo.HeaderXRequestID), line[utils.FieldRequestID])
+}
+
+func TestSetup(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := Setup(ctx, ExporterFile, "")
+	assert.Error(t, err, "Expecting the file exporter to need a file")
+	_, err = Setup(ctx, "jaeger", "")
+	assert.ErrorContains(t, err, "unknown trace exporter")
+
+	file := path.Join(t.TempDir(), "traces.json")
+	stop, err := Setup(ctx, ExporterFile, file)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, s := otel.Tracer("test").Start(ctx, "offline")
+	s.End()
+	assert.NoError(t, stop(ctx), "Expecting the pending spans to be flushed")
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	spans := decodeSpans(t, f)
+	assert.Contains(t, spans, "offline")
+}
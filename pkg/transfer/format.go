@@ -0,0 +1,94 @@
+package transfer
+
+import (
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+)
+
+// Format is the format the fruits are imported from and exported to
+type Format string
+
+const (
+	// FormatCSV is comma separated values having a header row naming the columns
+	FormatCSV Format = "csv"
+	// FormatNDJSON is a JSON object per line
+	FormatNDJSON Format = "ndjson"
+	// FormatYAML is the dbfixture YAML layout, like the data.yaml
+	FormatYAML Format = "yaml"
+	// FormatSQL is an INSERT statement per line, the fruits could only be
+	// exported to it
+	FormatSQL Format = "sql"
+)
+
+// Formats are the known formats
+var Formats = []Format{FormatCSV, FormatNDJSON, FormatYAML, FormatSQL}
+
+// contentTypes are the media types of the formats, the first one is the
+// preferred one
+var contentTypes = map[Format][]string{
+	FormatCSV:    {"text/csv"},
+	FormatNDJSON: {"application/x-ndjson", "application/ndjson", "application/jsonl"},
+	FormatYAML:   {"application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml"},
+	FormatSQL:    {"application/sql"},
+}
+
+// extensions are the file extensions of the formats
+var extensions = map[Format][]string{
+	FormatCSV:    {".csv"},
+	FormatNDJSON: {".ndjson", ".jsonl"},
+	FormatYAML:   {".yaml", ".yml"},
+	FormatSQL:    {".sql"},
+}
+
+// ParseFormat returns the Format named s
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	return "", apperrors.BadRequest(fmt.Errorf("unknown format %q, allowed formats are %v", s, Formats))
+}
+
+// ContentType returns the media type of the format
+func (f Format) ContentType() string {
+	return contentTypes[f][0]
+}
+
+// Extension returns the file extension of the format
+func (f Format) Extension() string {
+	return extensions[f][0]
+}
+
+// FormatOfContentType returns the Format having the media type of the
+// Content-Type header value ct
+func FormatOfContentType(ct string) (Format, error) {
+	mt, _, err := mime.ParseMediaType(ct)
+	if err == nil {
+		for f, types := range contentTypes {
+			for _, t := range types {
+				if mt == t {
+					return f, nil
+				}
+			}
+		}
+	}
+	return "", apperrors.BadRequest(fmt.Errorf("unknown content type %q, set the format", ct))
+}
+
+// FormatOfFile returns the Format having the extension of the file name
+func FormatOfFile(name string) (Format, error) {
+	ext := strings.ToLower(filepath.Ext(name))
+	for f, exts := range extensions {
+		for _, e := range exts {
+			if ext == e {
+				return f, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("unknown format of %q, set the format", name)
+}
@@ -0,0 +1,170 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"gopkg.in/yaml.v3"
+)
+
+// row are the fields of a fruit that are imported, the other fields like
+// the id are ignored so that the exports could be imported
+type row struct {
+	Name   string `json:"name" yaml:"name"`
+	Season string `json:"season" yaml:"season"`
+	Emoji  string `json:"emoji" yaml:"emoji"`
+}
+
+func (r row) fruit() *db.Fruit {
+	return &db.Fruit{Name: r.Name, Season: r.Season, Emoji: r.Emoji}
+}
+
+// NewReader returns the db.ImportReader reading the fruits in the format from r
+func NewReader(format Format, r io.Reader) (db.ImportReader, error) {
+	switch format {
+	case FormatCSV:
+		cr := csv.NewReader(r)
+		cr.ReuseRecord = true
+		return &csvReader{r: cr}, nil
+	case FormatNDJSON:
+		return &ndjsonReader{r: bufio.NewReader(r)}, nil
+	case FormatYAML:
+		return &yamlReader{d: yaml.NewDecoder(r)}, nil
+	case FormatSQL:
+		return nil, apperrors.BadRequest(errors.New("fruits could not be imported from sql, use csv, ndjson or yaml"))
+	default:
+		_, err := ParseFormat(string(format))
+		return nil, err
+	}
+}
+
+// csvReader reads the CSV having a header row, the name and season columns
+// are required, the columns that are not imported are ignored
+type csvReader struct {
+	r *csv.Reader
+	//columns is the index of each imported column, -1 when it is missing
+	columns map[string]int
+}
+
+func (cr *csvReader) Next() (*db.ImportRow, error) {
+	if cr.columns == nil {
+		if err := cr.readHeader(); err != nil {
+			return nil, err
+		}
+	}
+	record, err := cr.r.Read()
+	var perr *csv.ParseError
+	switch {
+	case errors.As(err, &perr):
+		return &db.ImportRow{Line: perr.StartLine, Err: perr.Err}, nil
+	case err != nil:
+		return nil, err
+	}
+	line, _ := cr.r.FieldPos(0)
+	value := func(column string) string {
+		if i := cr.columns[column]; i >= 0 {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	return &db.ImportRow{
+		Line:  line,
+		Fruit: row{Name: value("name"), Season: value("season"), Emoji: value("emoji")}.fruit(),
+	}, nil
+}
+
+func (cr *csvReader) readHeader() error {
+	header, err := cr.r.Read()
+	if errors.Is(err, io.EOF) {
+		return apperrors.BadRequest(errors.New("invalid CSV, the header row is missing"))
+	}
+	if err != nil {
+		return apperrors.BadRequest(fmt.Errorf("invalid CSV header, %w", err))
+	}
+	cr.columns = map[string]int{"name": -1, "season": -1, "emoji": -1}
+	for i, h := range header {
+		//the spreadsheets often start the file with a byte order mark
+		h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		if j, ok := cr.columns[h]; ok && j < 0 {
+			cr.columns[h] = i
+		}
+	}
+	for _, required := range []string{"name", "season"} {
+		if cr.columns[required] < 0 {
+			return apperrors.BadRequest(fmt.Errorf("invalid CSV header, the %s column is missing", required))
+		}
+	}
+	return nil
+}
+
+// ndjsonReader reads a JSON object per line, the blank lines are ignored
+type ndjsonReader struct {
+	r    *bufio.Reader
+	line int
+}
+
+func (nr *ndjsonReader) Next() (*db.ImportRow, error) {
+	for {
+		b, err := nr.r.ReadBytes('\n')
+		if len(b) == 0 && err != nil {
+			return nil, err
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		nr.line++
+		b = bytes.TrimSpace(b)
+		if len(b) == 0 {
+			continue
+		}
+		var r row
+		if err := json.Unmarshal(b, &r); err != nil {
+			return &db.ImportRow{Line: nr.line, Err: fmt.Errorf("invalid JSON, %w", err)}, nil
+		}
+		return &db.ImportRow{Line: nr.line, Fruit: r.fruit()}, nil
+	}
+}
+
+// yamlReader reads the dbfixture YAML layout. A YAML document has to be read
+// whole, the input is read one document at a time so that the large fixtures
+// could be split in documents separated by ---.
+type yamlReader struct {
+	d    *yaml.Decoder
+	rows []yaml.Node
+}
+
+func (yr *yamlReader) Next() (*db.ImportRow, error) {
+	for len(yr.rows) == 0 {
+		var fixtures []struct {
+			Model string      `yaml:"model"`
+			Rows  []yaml.Node `yaml:"rows"`
+		}
+		if err := yr.d.Decode(&fixtures); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			return nil, apperrors.BadRequest(fmt.Errorf("invalid YAML, %w", err))
+		}
+		for _, fx := range fixtures {
+			if fx.Model != "Fruit" {
+				return nil, apperrors.BadRequest(fmt.Errorf("unknown model %q, only Fruit could be imported", fx.Model))
+			}
+			yr.rows = append(yr.rows, fx.Rows...)
+		}
+	}
+	node := yr.rows[0]
+	yr.rows = yr.rows[1:]
+	var r row
+	if err := node.Decode(&r); err != nil {
+		return &db.ImportRow{Line: node.Line, Err: fmt.Errorf("invalid row, %w", err)}, nil
+	}
+	return &db.ImportRow{Line: node.Line, Fruit: r.fruit()}, nil
+}
@@ -0,0 +1,164 @@
+package transfer
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+	"github.com/stretchr/testify/assert"
+)
+
+// readRow is the outcome of an ImportReader Next
+type readRow struct {
+	Line  int
+	Fruit string
+	Err   string
+}
+
+func TestReader(t *testing.T) {
+	testCases := map[string]struct {
+		format  Format
+		input   string
+		want    []readRow
+		wantErr string
+	}{
+		"csv": {
+			format: FormatCSV,
+			input: "\ufeffid,Name, season ,emoji\n" +
+				"1,Mango,Spring,U+1F96D\n" +
+				"2,\"Passion\nFruit\",Summer,\n" +
+				"3,Apple,Fall\n" +
+				"4,\"Pear\"x,Fall,\n" +
+				"5, Kiwi ,Winter,\n",
+			want: []readRow{
+				{Line: 2, Fruit: "Mango Spring U+1F96D"},
+				{Line: 3, Fruit: "Passion\nFruit Summer "},
+				{Line: 5, Err: "wrong number of fields"},
+				{Line: 6, Err: `extraneous or missing " in quoted-field`},
+				{Line: 7, Fruit: "Kiwi Winter "},
+			},
+		},
+		"csvWithoutEmoji": {
+			format: FormatCSV,
+			input:  "season,name\nSpring,Mango\n",
+			want:   []readRow{{Line: 2, Fruit: "Mango Spring "}},
+		},
+		"csvWithoutSeason": {
+			format:  FormatCSV,
+			input:   "name,emoji\nMango,U+1F96D\n",
+			wantErr: "invalid CSV header, the season column is missing",
+		},
+		"csvEmpty": {
+			format:  FormatCSV,
+			wantErr: "invalid CSV, the header row is missing",
+		},
+		"ndjson": {
+			format: FormatNDJSON,
+			input: `{"id":1,"name":"Mango","season":"Spring","emoji":"U+1F96D"}` + "\n" +
+				"\n" +
+				`{"name":"Apple",` + "\n" +
+				`{"name":"Kiwi","season":"Winter"}`,
+			want: []readRow{
+				{Line: 1, Fruit: "Mango Spring U+1F96D"},
+				{Line: 3, Err: "invalid JSON, unexpected end of JSON input"},
+				{Line: 4, Fruit: "Kiwi Winter "},
+			},
+		},
+		"yaml": {
+			format: FormatYAML,
+			input: `- model: Fruit
+  rows:
+    - _id: mango
+      name: Mango
+      season: Spring
+      emoji: U+1F96D
+      created_at: "{{ now }}"
+    - name: [Apple]
+---
+- model: Fruit
+  rows:
+    - name: Kiwi
+      season: Winter
+`,
+			want: []readRow{
+				{Line: 3, Fruit: "Mango Spring U+1F96D"},
+				{Line: 8, Err: "invalid row, yaml: unmarshal errors:\n  line 8: cannot unmarshal !!seq into string"},
+				{Line: 12, Fruit: "Kiwi Winter "},
+			},
+		},
+		"yamlUnknownModel": {
+			format:  FormatYAML,
+			input:   "- model: Vegetable\n  rows:\n    - name: Carrot\n",
+			wantErr: `unknown model "Vegetable", only Fruit could be imported`,
+		},
+		"yamlInvalid": {
+			format:  FormatYAML,
+			input:   "- model: Fruit\n  rows: [\n",
+			wantErr: "invalid YAML, yaml: line 2: did not find expected node content",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			rd, err := NewReader(tc.format, strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []readRow
+			for {
+				row, err := rd.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					if assert.NotEmpty(t, tc.wantErr, "Unexpected error %v", err) {
+						assert.EqualError(t, err, tc.wantErr)
+						assert.True(t, errors.As(err, new(*apperrors.BadRequestError)), "Expecting bad request but got %v", err)
+					}
+					return
+				}
+				r := readRow{Line: row.Line}
+				if row.Err != nil {
+					r.Err = row.Err.Error()
+				} else {
+					r.Fruit = row.Fruit.Name + " " + row.Fruit.Season + " " + row.Fruit.Emoji
+				}
+				got = append(got, r)
+			}
+			assert.Empty(t, tc.wantErr)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	testCases := map[string]struct {
+		contentType string
+		file        string
+		want        Format
+	}{
+		"csv":    {contentType: "text/csv; charset=utf-8", file: "fruits.CSV", want: FormatCSV},
+		"ndjson": {contentType: "application/x-ndjson", file: "fruits.jsonl", want: FormatNDJSON},
+		"yaml":   {contentType: "application/yaml", file: "data.yml", want: FormatYAML},
+		"json":   {contentType: "application/json", file: "fruits.json"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := FormatOfContentType(tc.contentType)
+			if tc.want == "" {
+				assert.Error(t, err)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tc.want, got)
+			}
+			got, err = FormatOfFile(tc.file)
+			if tc.want == "" {
+				assert.Error(t, err)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package transfer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/apperrors"
+	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect"
+	"github.com/uptrace/bun/dialect/mysqldialect"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// SQLDialects are the database types, as told by the dbType flag, whose SQL
+// statements could be exported
+var SQLDialects = []string{"sqlite", "pgsql", "mysql"}
+
+// SQLDialect returns the dialect of the database type dbType
+func SQLDialect(dbType string) (schema.Dialect, error) {
+	switch dbType {
+	case "sqlite":
+		return sqlitedialect.New(), nil
+	case "pgsql":
+		return pgdialect.New(), nil
+	case "mysql":
+		return mysqldialect.New(), nil
+	default:
+		return nil, apperrors.BadRequest(fmt.Errorf("unknown SQL dialect %q, allowed dialects are %v", dbType, SQLDialects))
+	}
+}
+
+// Writer writes the exported fruits in a format
+type Writer interface {
+	// Write writes the fruit
+	Write(f *db.Fruit) error
+	// Close writes what ends the export and flushes it, it does not close the
+	// underlying io.Writer
+	Close() error
+}
+
+// record are the fields of a fruit that are exported, named after the columns
+// so that the YAML could be loaded by dbfixture
+type record struct {
+	ID         int        `json:"id" yaml:"id"`
+	Name       string     `json:"name" yaml:"name"`
+	Season     string     `json:"season" yaml:"season"`
+	Emoji      string     `json:"emoji,omitempty" yaml:"emoji,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" yaml:"created_at"`
+	ModifiedAt *time.Time `json:"modified_at,omitempty" yaml:"modified_at,omitempty"`
+}
+
+func newRecord(f *db.Fruit) record {
+	r := record{
+		ID:        f.ID,
+		Name:      f.Name,
+		Season:    f.Season,
+		Emoji:     f.Emoji,
+		CreatedAt: f.CreatedAt.UTC(),
+	}
+	if !f.ModifiedAt.IsZero() {
+		modifiedAt := f.ModifiedAt.UTC()
+		r.ModifiedAt = &modifiedAt
+	}
+	return r
+}
+
+// NewWriter returns the Writer writing the fruits in the format to w, the SQL
+// statements are written in the dialect
+func NewWriter(format Format, w io.Writer, dialect schema.Dialect) (Writer, error) {
+	bw := bufio.NewWriter(w)
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(bw)
+		if err := cw.Write([]string{"id", "name", "season", "emoji", "created_at", "modified_at"}); err != nil {
+			return nil, err
+		}
+		return &csvWriter{w: bw, cw: cw}, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{w: bw, enc: json.NewEncoder(bw)}, nil
+	case FormatYAML:
+		return &yamlWriter{w: bw}, nil
+	case FormatSQL:
+		if dialect == nil {
+			return nil, errors.New("the dialect of the SQL statements is required")
+		}
+		return &sqlWriter{w: bw, fmter: schema.NewFormatter(dialect)}, nil
+	default:
+		_, err := ParseFormat(string(format))
+		return nil, err
+	}
+}
+
+type csvWriter struct {
+	w  *bufio.Writer
+	cw *csv.Writer
+}
+
+func (cw *csvWriter) Write(f *db.Fruit) error {
+	r := newRecord(f)
+	var modifiedAt string
+	if r.ModifiedAt != nil {
+		modifiedAt = r.ModifiedAt.Format(time.RFC3339Nano)
+	}
+	return cw.cw.Write([]string{strconv.Itoa(r.ID), r.Name, r.Season, r.Emoji, r.CreatedAt.Format(time.RFC3339Nano), modifiedAt})
+}
+
+func (cw *csvWriter) Close() error {
+	cw.cw.Flush()
+	if err := cw.cw.Error(); err != nil {
+		return err
+	}
+	return cw.w.Flush()
+}
+
+type ndjsonWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (nw *ndjsonWriter) Write(f *db.Fruit) error {
+	return nw.enc.Encode(newRecord(f))
+}
+
+func (nw *ndjsonWriter) Close() error {
+	return nw.w.Flush()
+}
+
+// yamlWriter writes the dbfixture YAML layout a row at a time, a YAML encoder
+// would need all the rows to write their list
+type yamlWriter struct {
+	w    *bufio.Writer
+	rows int
+}
+
+func (yw *yamlWriter) Write(f *db.Fruit) error {
+	if yw.rows == 0 {
+		if _, err := yw.w.WriteString("- model: Fruit\n  rows:\n"); err != nil {
+			return err
+		}
+	}
+	yw.rows++
+	b, err := yaml.Marshal(newRecord(f))
+	if err != nil {
+		return err
+	}
+	//indent the mapping as an item of the rows
+	for i, line := range strings.Split(strings.TrimSuffix(string(b), "\n"), "\n") {
+		prefix := "      "
+		if i == 0 {
+			prefix = "    - "
+		}
+		if _, err := yw.w.WriteString(prefix + line + "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (yw *yamlWriter) Close() error {
+	if yw.rows == 0 {
+		if _, err := yw.w.WriteString("- model: Fruit\n  rows: []\n"); err != nil {
+			return err
+		}
+	}
+	return yw.w.Flush()
+}
+
+// sqlWriter writes an INSERT statement per fruit keeping its id, the fruits
+// table is expected to be created by the migrations
+type sqlWriter struct {
+	w     *bufio.Writer
+	fmter schema.Formatter
+	rows  int
+}
+
+func (sw *sqlWriter) Write(f *db.Fruit) error {
+	sw.rows++
+	r := newRecord(f)
+	_, err := sw.w.WriteString(sw.fmter.FormatQuery("INSERT INTO ? (?, ?, ?, ?, ?, ?) VALUES (?, ?, ?, ?, ?, ?);\n",
+		bun.Ident("fruits"), bun.Ident("id"), bun.Ident("name"), bun.Ident("season"), bun.Ident("emoji"), bun.Ident("created_at"), bun.Ident("modified_at"),
+		r.ID, r.Name, r.Season, r.Emoji, r.CreatedAt, r.ModifiedAt))
+	return err
+}
+
+func (sw *sqlWriter) Close() error {
+	//the ids are inserted, the PostgreSQL sequence has to catch up with them
+	if sw.rows > 0 && sw.fmter.Dialect().Name() == dialect.PG {
+		if _, err := sw.w.WriteString(sw.fmter.FormatQuery("SELECT setval(pg_get_serial_sequence('fruits', 'id'), (SELECT MAX(?) FROM ?));\n",
+			bun.Ident("id"), bun.Ident("fruits"))); err != nil {
+			return err
+		}
+	}
+	return sw.w.Flush()
+}
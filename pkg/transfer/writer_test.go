@@ -0,0 +1,227 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"github.com/kameshsampath/go-fruits-api/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/uptrace/bun/dbfixture"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+)
+
+func testFruits() db.Fruits {
+	createdAt := time.Date(2023, 1, 10, 9, 30, 0, 0, time.UTC)
+	return db.Fruits{
+		{ID: 1, Name: "Mango", Season: "Spring", Emoji: "U+1F96D", CreatedAt: createdAt},
+		{ID: 3, Name: "Passion's \"Fruit\"", Season: "Summer", CreatedAt: createdAt, ModifiedAt: createdAt.Add(time.Hour)},
+	}
+}
+
+func TestWriter(t *testing.T) {
+	testCases := map[string]struct {
+		format  Format
+		dialect string
+		fruits  db.Fruits
+		want    string
+	}{
+		"csv": {
+			format: FormatCSV,
+			fruits: testFruits(),
+			want: "id,name,season,emoji,created_at,modified_at\n" +
+				"1,Mango,Spring,U+1F96D,2023-01-10T09:30:00Z,\n" +
+				"3,\"Passion's \"\"Fruit\"\"\",Summer,,2023-01-10T09:30:00Z,2023-01-10T10:30:00Z\n",
+		},
+		"csvEmpty": {
+			format: FormatCSV,
+			want:   "id,name,season,emoji,created_at,modified_at\n",
+		},
+		"ndjson": {
+			format: FormatNDJSON,
+			fruits: testFruits(),
+			want: `{"id":1,"name":"Mango","season":"Spring","emoji":"U+1F96D","created_at":"2023-01-10T09:30:00Z"}` + "\n" +
+				`{"id":3,"name":"Passion's \"Fruit\"","season":"Summer","created_at":"2023-01-10T09:30:00Z","modified_at":"2023-01-10T10:30:00Z"}` + "\n",
+		},
+		"yaml": {
+			format: FormatYAML,
+			fruits: testFruits(),
+			want: `- model: Fruit
+  rows:
+    - id: 1
+      name: Mango
+      season: Spring
+      emoji: U+1F96D
+      created_at: 2023-01-10T09:30:00Z
+    - id: 3
+      name: Passion's "Fruit"
+      season: Summer
+      created_at: 2023-01-10T09:30:00Z
+      modified_at: 2023-01-10T10:30:00Z
+`,
+		},
+		"yamlEmpty": {
+			format: FormatYAML,
+			want:   "- model: Fruit\n  rows: []\n",
+		},
+		"sqlPostgres": {
+			format:  FormatSQL,
+			dialect: "pgsql",
+			fruits:  testFruits(),
+			want: `INSERT INTO "fruits" ("id", "name", "season", "emoji", "created_at", "modified_at") VALUES (1, 'Mango', 'Spring', 'U+1F96D', '2023-01-10 09:30:00+00:00', NULL);
+INSERT INTO "fruits" ("id", "name", "season", "emoji", "created_at", "modified_at") VALUES (3, 'Passion''s "Fruit"', 'Summer', '', '2023-01-10 09:30:00+00:00', '2023-01-10 10:30:00+00:00');
+SELECT setval(pg_get_serial_sequence('fruits', 'id'), (SELECT MAX("id") FROM "fruits"));
+`,
+		},
+		"sqlMySQL": {
+			format:  FormatSQL,
+			dialect: "mysql",
+			fruits:  testFruits()[:1],
+			want:    "INSERT INTO `fruits` (`id`, `name`, `season`, `emoji`, `created_at`, `modified_at`) VALUES (1, 'Mango', 'Spring', 'U+1F96D', '2023-01-10 09:30:00', NULL);\n",
+		},
+		"sqlEmpty": {
+			format:  FormatSQL,
+			dialect: "pgsql",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			var w Writer
+			var err error
+			if tc.dialect != "" {
+				dialect, derr := SQLDialect(tc.dialect)
+				if derr != nil {
+					t.Fatal(derr)
+				}
+				w, err = NewWriter(tc.format, &buf, dialect)
+			} else {
+				w, err = NewWriter(tc.format, &buf, nil)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range tc.fruits {
+				assert.NoError(t, w.Write(f))
+			}
+			assert.NoError(t, w.Close())
+			assert.Equal(t, tc.want, buf.String())
+		})
+	}
+}
+
+// TestRoundTrip checks that the exports are imported back as they were
+// exported, and that the YAML and SQL exports load in a database
+func TestRoundTrip(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	log := utils.LogSetup(os.Stdout, utils.LookupEnvOrString("TEST_LOG_LEVEL", "info"))
+	export := func(format Format) *bytes.Buffer {
+		var buf bytes.Buffer
+		w, err := NewWriter(format, &buf, sqlitedialect.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range testFruits() {
+			if err := w.Write(f); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return &buf
+	}
+	values := func(fruits db.Fruits) []db.ImportValues {
+		var got []db.ImportValues
+		for _, f := range fruits {
+			got = append(got, db.ImportValues{Name: f.Name, Season: f.Season, Emoji: f.Emoji})
+		}
+		return got
+	}
+	want := values(testFruits())
+
+	for _, format := range []Format{FormatCSV, FormatNDJSON, FormatYAML} {
+		t.Run(string(format), func(t *testing.T) {
+			rd, err := NewReader(format, export(format))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fruits db.Fruits
+			for {
+				row, err := rd.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if !assert.NoError(t, err) || !assert.NoError(t, row.Err) {
+					return
+				}
+				fruits = append(fruits, row.Fruit)
+			}
+			assert.Equal(t, want, values(fruits))
+		})
+	}
+
+	newDB := func(t *testing.T) (*db.Config, db.FruitRepository) {
+		dbc := db.New(
+			db.WithLogger(log),
+			db.WithDBType("sqlite"),
+			db.WithDBFile(path.Join(t.TempDir(), "roundtrip.db")))
+		if err := dbc.Init(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return dbc, db.NewBunFruitRepository(dbc)
+	}
+	exported := func(t *testing.T, repo db.FruitRepository) {
+		var fruits db.Fruits
+		assert.NoError(t, repo.Export(ctx, func(f *db.Fruit) error {
+			fruits = append(fruits, f)
+			return nil
+		}))
+		if assert.Len(t, fruits, 2) {
+			assert.Equal(t, []int{1, 3}, []int{fruits[0].ID, fruits[1].ID}, "Expecting the ids to be kept")
+			assert.Equal(t, want, values(fruits))
+		}
+	}
+
+	t.Run("dbfixture", func(t *testing.T) {
+		dbc, repo := newDB(t)
+		dbc.DB.RegisterModel((*db.Fruit)(nil))
+		dir := t.TempDir()
+		if err := os.WriteFile(path.Join(dir, "fruits.yaml"), export(FormatYAML).Bytes(), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := dbfixture.New(dbc.DB).Load(ctx, os.DirFS(dir), "fruits.yaml"); err != nil {
+			t.Fatal(err)
+		}
+		exported(t, repo)
+	})
+
+	t.Run("sql", func(t *testing.T) {
+		dbc, repo := newDB(t)
+		sc := bufio.NewScanner(export(FormatSQL))
+		for sc.Scan() {
+			if _, err := dbc.DB.ExecContext(ctx, sc.Text()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		exported(t, repo)
+		mango, err := repo.Get(ctx, 1)
+		if assert.NoError(t, err) {
+			assert.True(t, testFruits()[0].CreatedAt.Equal(mango.CreatedAt), "Expecting the creation time to be kept but got %s", mango.CreatedAt)
+		}
+		//the next id follows the inserted ones
+		f := &db.Fruit{Name: "Kiwi", Season: "Winter"}
+		if assert.NoError(t, repo.Create(ctx, f)) {
+			assert.Equal(t, 4, f.ID)
+		}
+	})
+}
@@ -1,18 +1,18 @@
 package utils
 
-import "github.com/labstack/echo/v4"
+import "github.com/kameshsampath/go-fruits-api/pkg/apperrors"
 
-// NewHTTPError example
-func NewHTTPError(c echo.Context, status int, err error) {
-	httpErr := HTTPError{
-		Code:    status,
-		Message: err.Error(),
-	}
-	c.JSON(status, httpErr)
-}
+// MIMEApplicationProblemJSON is the media type of the problem responses
+const MIMEApplicationProblemJSON = "application/problem+json"
 
-// HTTPError example
-type HTTPError struct {
-	Code    int    `json:"code" example:"400"`
-	Message string `json:"message" example:"status bad request"`
+// Problem is the RFC 7807 problem details response of a failed request
+type Problem struct {
+	Type     string                 `json:"type" example:"https://github.com/kameshsampath/go-fruits-api/blob/main/docs/problems.md#not-found"`
+	Title    string                 `json:"title" example:"Not Found"`
+	Status   int                    `json:"status" example:"404"`
+	Detail   string                 `json:"detail,omitempty" example:"fruit with id 5 not found"`
+	Instance string                 `json:"instance,omitempty" example:"/api/fruits/5"`
+	Errors   []apperrors.FieldError `json:"errors,omitempty"`
+	// ExistingID is the id of the existing resource a conflicting change collides with
+	ExistingID interface{} `json:"existing_id,omitempty" swaggertype:"integer" example:"1"`
 }
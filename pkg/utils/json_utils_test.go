@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch(t *testing.T) {
+	testCases := map[string]struct {
+		doc   string
+		patch string
+		want  string
+	}{
+		"replaceValue": {
+			doc:   `{"name":"Mango","season":"Spring"}`,
+			patch: `{"season":"Summer"}`,
+			want:  `{"name":"Mango","season":"Summer"}`,
+		},
+		"addValue": {
+			doc:   `{"name":"Mango"}`,
+			patch: `{"emoji":"U+1F96D"}`,
+			want:  `{"name":"Mango","emoji":"U+1F96D"}`,
+		},
+		"removeValue": {
+			doc:   `{"name":"Mango","emoji":"U+1F96D"}`,
+			patch: `{"emoji":null}`,
+			want:  `{"name":"Mango"}`,
+		},
+		"nestedValue": {
+			doc:   `{"a":{"b":"c","d":"e"}}`,
+			patch: `{"a":{"b":"x","d":null}}`,
+			want:  `{"a":{"b":"x"}}`,
+		},
+		"replaceDocument": {
+			doc:   `{"name":"Mango"}`,
+			patch: `["Mango"]`,
+			want:  `["Mango"]`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := MergePatch([]byte(tc.doc), []byte(tc.patch))
+			if assert.NoError(t, err) {
+				assert.JSONEq(t, tc.want, string(got))
+			}
+		})
+	}
+}
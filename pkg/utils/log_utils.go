@@ -1,15 +1,37 @@
 package utils
 
 import (
+	"context"
 	"io"
-
 	"log"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
-//LogSetup sets up the logging for the application
+const (
+	// LogFormatJSON logs a JSON object per line, meant for the log aggregators
+	LogFormatJSON = "json"
+	// LogFormatText logs human readable lines
+	LogFormatText = "text"
+
+	// FieldRequestID is the log field holding the request id
+	FieldRequestID = "request_id"
+	// FieldTraceID is the log field holding the id of the trace
+	FieldTraceID = "trace_id"
+	// FieldSpanID is the log field holding the id of the current span
+	FieldSpanID = "span_id"
+)
+
+// LogSetup sets up the JSON logging for the application
 func LogSetup(out io.Writer, level string) *logrus.Logger {
+	return LogSetupWithFormat(out, level, LogFormatJSON)
+}
+
+// LogSetupWithFormat sets up the logging for the application using the format,
+// either LogFormatJSON or LogFormatText
+func LogSetupWithFormat(out io.Writer, level string, format string) *logrus.Logger {
 	lvl, err := logrus.ParseLevel(level)
 
 	if err != nil {
@@ -17,15 +39,56 @@ func LogSetup(out io.Writer, level string) *logrus.Logger {
 		lvl = logrus.WarnLevel
 	}
 
-	log := &logrus.Logger{
-		Formatter: &logrus.TextFormatter{
+	var formatter logrus.Formatter
+	switch format {
+	case LogFormatText:
+		formatter = &logrus.TextFormatter{
 			FullTimestamp:   true,
-			TimestampFormat: "2006-01-02 15:15:10",
-		},
-		Out: out,
+			TimestampFormat: "2006-01-02 15:04:05",
+		}
+	default:
+		if format != LogFormatJSON {
+			log.Printf("Unable to use the %s log format. Defaulting to %s.", format, LogFormatJSON)
+		}
+		formatter = &logrus.JSONFormatter{
+			TimestampFormat: time.RFC3339Nano,
+		}
+	}
+
+	log := &logrus.Logger{
+		Formatter: formatter,
+		Out:       out,
+		Hooks:     make(logrus.LevelHooks),
 		//ReportCaller: true,
 		Level: lvl,
 	}
 
 	return log
 }
+
+type requestIDKey struct{}
+
+// WithRequestID returns the copy of ctx carrying the id of the request it serves
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request id carried by ctx, it is empty outside of the requests
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// LogEntry returns the log entry tagged with the request id and the trace
+// context carried by ctx
+func LogEntry(ctx context.Context, log *logrus.Logger) *logrus.Entry {
+	fields := logrus.Fields{}
+	if id := RequestID(ctx); id != "" {
+		fields[FieldRequestID] = id
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields[FieldTraceID] = sc.TraceID().String()
+		fields[FieldSpanID] = sc.SpanID().String()
+	}
+	return log.WithContext(ctx).WithFields(fields)
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogSetup(t *testing.T) {
+	var out bytes.Buffer
+	log := LogSetup(&out, "info")
+	ctx := WithRequestID(context.Background(), "f00d")
+	LogEntry(ctx, log).Info("hello")
+	LogEntry(context.Background(), log).Debug("hidden")
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("Expecting a single JSON log line but got %q, %v", out.String(), err)
+	}
+	assert.Equal(t, "hello", got["msg"])
+	assert.Equal(t, "info", got["level"])
+	assert.Equal(t, "f00d", got[FieldRequestID])
+	if ts, ok := got["time"].(string); assert.True(t, ok) {
+		_, err := time.Parse(time.RFC3339Nano, ts)
+		assert.NoError(t, err)
+	}
+}
+
+func TestLogSetupText(t *testing.T) {
+	var out bytes.Buffer
+	log := LogSetupWithFormat(&out, "info", LogFormatText)
+	LogEntry(context.Background(), log).Info("hello")
+	assert.Regexp(t, regexp.MustCompile(`^time="\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}" level=info msg=hello\n$`), out.String())
+	assert.NotContains(t, out.String(), FieldRequestID, "Expecting no request id outside of the requests")
+}
@@ -1,11 +1,14 @@
 package utils
 
 import (
+	"log"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
-//Reverse reverses the String
+// Reverse reverses the String
 func Reverse(s string) string {
 	var sb strings.Builder
 	runes := []rune(s)
@@ -15,8 +18,8 @@ func Reverse(s string) string {
 	return sb.String()
 }
 
-//LookupEnvOrString looks up an environment variable if not found
-//returns defaultVal
+// LookupEnvOrString looks up an environment variable if not found
+// returns defaultVal
 func LookupEnvOrString(envName, defaultVal string) string {
 	if val, ok := os.LookupEnv(envName); ok {
 		return val
@@ -24,3 +27,33 @@ func LookupEnvOrString(envName, defaultVal string) string {
 
 	return defaultVal
 }
+
+// LookupEnvOrDuration looks up an environment variable holding a duration
+// like "5s", returns defaultVal when it is not found or not a valid duration
+func LookupEnvOrDuration(envName string, defaultVal time.Duration) time.Duration {
+	val, ok := os.LookupEnv(envName)
+	if !ok {
+		return defaultVal
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		log.Printf("Unable to use the %s %q, %v. Defaulting to %s.", envName, val, err, defaultVal)
+		return defaultVal
+	}
+	return d
+}
+
+// LookupEnvOrInt looks up an environment variable holding an integer,
+// returns defaultVal when it is not found or not a valid integer
+func LookupEnvOrInt(envName string, defaultVal int) int {
+	val, ok := os.LookupEnv(envName)
+	if !ok {
+		return defaultVal
+	}
+	i, err := strconv.Atoi(val)
+	if err != nil {
+		log.Printf("Unable to use the %s %q, %v. Defaulting to %d.", envName, val, err, defaultVal)
+		return defaultVal
+	}
+	return i
+}
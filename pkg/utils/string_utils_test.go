@@ -3,6 +3,7 @@ package utils
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -46,3 +47,61 @@ func TestLookupEnvOrDefault(t *testing.T) {
 		})
 	}
 }
+
+func TestLookupEnvOrDuration(t *testing.T) {
+	os.Setenv("BAR_TIMEOUT", "250ms")
+	os.Setenv("BAD_TIMEOUT", "ten seconds")
+	testCases := map[string]struct {
+		name string
+		want time.Duration
+	}{
+		"defaults": {
+			name: "FOO_TIMEOUT",
+			want: time.Second,
+		},
+		"nodefaults": {
+			name: "BAR_TIMEOUT",
+			want: 250 * time.Millisecond,
+		},
+		"invalid": {
+			name: "BAD_TIMEOUT",
+			want: time.Second,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got := LookupEnvOrDuration(tc.name, time.Second)
+			assert.Equalf(t, tc.want, got, "Got %s but want %s", got, tc.want)
+		})
+	}
+}
+
+func TestLookupEnvOrInt(t *testing.T) {
+	os.Setenv("BAR_DAYS", "7")
+	os.Setenv("BAD_DAYS", "seven")
+	testCases := map[string]struct {
+		name string
+		want int
+	}{
+		"defaults": {
+			name: "FOO_DAYS",
+			want: 30,
+		},
+		"nodefaults": {
+			name: "BAR_DAYS",
+			want: 7,
+		},
+		"invalid": {
+			name: "BAD_DAYS",
+			want: 30,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got := LookupEnvOrInt(tc.name, 30)
+			assert.Equalf(t, tc.want, got, "Got %d but want %d", got, tc.want)
+		})
+	}
+}
@@ -7,12 +7,15 @@ import (
 	"fmt"
 	"net/http"
 	"os/signal"
-	"path"
+	"path/filepath"
 	"time"
 
 	_ "github.com/kameshsampath/go-fruits-api/docs"
+	"github.com/kameshsampath/go-fruits-api/pkg/config"
 	"github.com/kameshsampath/go-fruits-api/pkg/db"
+	"github.com/kameshsampath/go-fruits-api/pkg/metrics"
 	"github.com/kameshsampath/go-fruits-api/pkg/routes"
+	"github.com/kameshsampath/go-fruits-api/pkg/tracing"
 	"github.com/kameshsampath/go-fruits-api/pkg/utils"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -23,13 +26,11 @@ import (
 	echoSwagger "github.com/swaggo/echo-swagger"
 
 	_ "github.com/uptrace/bun"
-	"github.com/uptrace/bun/dbfixture"
 )
 
 var (
-	log            *logrus.Logger
-	httpListenPort = "8080"
-	router         *echo.Echo
+	log    *logrus.Logger
+	router *echo.Echo
 )
 
 // @title Fruits API
@@ -47,99 +48,206 @@ var (
 // @query.collection.format multi
 // @schemes http https
 func main() {
-	var v, dbType, dbFile, dataDir string
-	flag.StringVar(&dbType, "dbType", utils.LookupEnvOrString("FRUITS_DB_TYPE", "sqlite"), "The database to use. Valid values are sqlite, pgsql, mysql")
-	flag.StringVar(&dbFile, "dbPath", utils.LookupEnvOrString("FRUITS_DB_FILE", "/data/db"), "Sqlite DB file")
-	flag.StringVar(&dataDir, "dataDir", "", "The data dir that will have the 'data.yaml' that will be loaded on to the Fruits table.")
-	flag.StringVar(&v, "level", utils.LookupEnvOrString("LOG_LEVEL", logrus.InfoLevel.String()), "The log level to use. Allowed values trace,debug,info,warn,fatal,panic.")
-	flag.Parse()
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:], os.LookupEnv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
 	ctx := context.Background()
-	log = utils.LogSetup(os.Stdout, v)
-	dbc := db.New(
-		db.WithLogger(log),
-		db.WithDBType(dbType),
-		db.WithDBFile(dbFile))
-	dbc.Init(ctx)
-
-	//marker file to ensure we don't preload the data again on each
-	//update of the application
-	_, err := os.Stat(path.Join("/data", "db", ".loaded"))
-	if dataDir != "" && errors.Is(err, os.ErrNotExist) {
-		log.Info("Attempting to preload data")
-		fixtures := dbfixture.New(dbc.DB, dbfixture.WithTruncateTables())
-		if err := fixtures.Load(ctx, os.DirFS(dataDir), "data.yaml"); err != nil {
-			log.Warnf("unable to preload the data,%v", err)
+	log = utils.LogSetupWithFormat(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+
+	//the config command needs no database
+	if flag.Arg(0) == "config" {
+		if err := runConfig(os.Stdout, cfg, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	//the copy command connects to the databases of its URLs
+	if flag.Arg(0) == "copy" {
+		if err := runCopy(ctx, os.Stdout, log, cfg, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	//the restore command replaces the database file, it must not be opened
+	if flag.Arg(0) == "restore" {
+		if err := runRestore(ctx, os.Stdout, log, cfg, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	//commands like migrate manage the schema themselves
+	if flag.NArg() > 0 {
+		dbc := db.New(append(cfg.DBOptions(),
+			db.WithLogger(log),
+			db.WithAutoMigrate(false))...)
+		if err := dbc.Init(ctx); err != nil {
+			log.Fatal(err)
 		}
-		_, err := os.Create(path.Join("/data", "db", ".loaded"))
-		if err != nil {
-			log.Errorf("Error creating marker file %v", err)
+		if err := runCommand(ctx, os.Stdout, dbc, flag.Args()); err != nil {
+			log.Fatal(err)
 		}
-	} else {
-		log.Info("Data already loaded, skipping preload.")
+		return
+	}
+
+	dbc := db.New(append(cfg.DBOptions(), db.WithLogger(log))...)
+
+	stopTracing, err := tracing.Setup(ctx, cfg.Tracing.Exporter, cfg.Tracing.File)
+	if err != nil {
+		log.Fatalf("Error setting up the tracing, %v", err)
 	}
 
+	m := metrics.New()
+	startup := routes.NewStartup()
+	//the repository is set once the database is initialized
+	endpoints := routes.NewEndpoints(nil, log)
+	endpoints.SQLDialect = cfg.DB.Type
+
 	router = echo.New()
-	router.Use(middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
-		LogURI:    true,
-		LogStatus: true,
-		LogError:  true,
-		LogValuesFunc: func(c echo.Context, values middleware.RequestLoggerValues) error {
-			log.WithFields(logrus.Fields{
-				"URI":    values.URI,
-				"status": values.Status,
-			}).Debug("request")
-
-			return nil
-		},
-	}))
+	router.Validator = routes.NewValidator()
+	router.HTTPErrorHandler = routes.NewHTTPErrorHandler(log)
+	router.Use(routes.RequestID())
+	router.Use(tracing.Middleware())
+	router.Use(m.Middleware())
+	router.Use(routes.AccessLog(log))
 	router.Use(middleware.Recover())
-	addRoutes(dbc)
+	addRoutes(endpoints, startup, cfg.DB.Timeout, cfg.DB.TransferTimeout)
 
 	// Start server
 	go func() {
-		if p, ok := os.LookupEnv("HTTP_LISTEN_PORT"); ok {
-			httpListenPort = p
-		}
-		if err := router.Start(fmt.Sprintf(":%s", httpListenPort)); err != nil && err.Error() != http.ErrServerClosed.Error() {
+		if err := router.Start(fmt.Sprintf(":%s", cfg.HTTP.Port)); err != nil && err.Error() != http.ErrServerClosed.Error() {
 			router.Logger.Fatal("shutting down the server")
 		}
 	}()
 
+	//the admin endpoints are served on their own port so that they are not exposed along with the API
+	admin := echo.New()
+	admin.HideBanner = true
+	admin.HTTPErrorHandler = routes.NewHTTPErrorHandler(log)
+	admin.GET("/metrics", echo.WrapHandler(m.Handler()))
+	backups := routes.NewBackups(dbc, db.BackupDir(cfg.Backup.Dir), cfg.Backup.Compress, log)
+	admin.POST("/backups", backups.CreateBackup, startup.Gate())
+	admin.GET("/backups", backups.ListBackups)
+	admin.GET("/backups/:name", backups.DownloadBackup)
+	if cfg.HTTP.AdminPort != "" {
+		go func() {
+			if err := admin.Start(fmt.Sprintf(":%s", cfg.HTTP.AdminPort)); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("Error starting the admin listener, %v", err)
+			}
+		}()
+	}
+
+	//the database is initialized while the API is served, so that the startup
+	//probe reports that the application is starting until it is reachable and migrated
+	initCtx, stopInit := context.WithCancel(ctx)
+	defer stopInit()
+	go func() {
+		if err := dbc.Init(initCtx); err != nil {
+			if initCtx.Err() == nil {
+				log.Fatalf("Error initializing the database, %v", err)
+			}
+			return
+		}
+		dbc.DB.AddQueryHook(m.QueryHook())
+		preload(initCtx, dbc, cfg.Seed)
+
+		repo := db.NewBunFruitRepository(dbc)
+		if cfg.Trash.PurgeAfterDays > 0 {
+			go db.PurgeTrash(initCtx, log, repo, time.Duration(cfg.Trash.PurgeAfterDays)*24*time.Hour, cfg.Trash.PurgeInterval)
+		}
+		if cfg.Backup.Interval > 0 {
+			go db.ScheduleBackups(initCtx, log, dbc, db.BackupDir(cfg.Backup.Dir), cfg.Backup.Interval, cfg.Backup.Keep, cfg.Backup.Compress)
+		}
+		m.RegisterDB(dbc, repo)
+		endpoints.Repo = repo
+		startup.Started()
+		log.Info("Started serving the API")
+	}()
+
 	// Wait for interrupt signal to gracefully shutdown the server with a timeout of 10 seconds.
 	// Use a buffered channel to avoid missing signals as recommended for signal.Notify
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt)
 	<-quit
+	stopInit()
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	if err := router.Shutdown(ctx); err != nil {
 		router.Logger.Fatal(err)
 	}
+	if err := admin.Shutdown(ctx); err != nil {
+		log.Errorf("Error shutting down the admin listener, %v", err)
+	}
+	if err := stopTracing(ctx); err != nil {
+		log.Errorf("Error flushing the traces, %v", err)
+	}
 }
 
-func addRoutes(dbc *db.Config) {
-	endpoints := routes.NewEndpoints(dbc)
+// preload seeds the data.yaml of the seed data dir unless it was already, the
+// seeds table records it so that it is not seeded again on each update of the
+// application and the fruits users changed are left alone
+func preload(ctx context.Context, dbc *db.Config, seed config.Seed) {
+	if seed.DataDir == "" {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(seed.DataDir, "data.yaml"))
+	if err != nil {
+		log.Warnf("Unable to read the data to preload, %v", err)
+		return
+	}
+	res, err := dbc.Seed(ctx, "data", data, db.SeedOptions{Mode: db.SeedIfNew, Validator: routes.NewValidator()})
+	if err != nil {
+		log.Warnf("Unable to preload the data, %v", err)
+		return
+	}
+	if res.Skipped != "" {
+		log.Infof("Skipping the preload of the data, %s", res.Skipped)
+		return
+	}
+	log.Infof("Preloaded the data, inserted %d fruits, %d were already there", res.Inserted, res.Unchanged)
+}
 
-	v1 := router.Group("/api")
+func addRoutes(endpoints *routes.Endpoints, startup *routes.Startup, dbTimeout, transferTimeout time.Duration) {
+	v1 := router.Group("/api", routes.Actor())
 	{
 		//Health Endpoints accessible via /api/health
-		health := v1.Group("/health")
+		health := v1.Group("/health", routes.RequestTimeout(dbTimeout))
 		{
 			health.GET("/live", endpoints.Live)
-			health.GET("/ready", endpoints.Ready)
+			health.GET("/startup", startup.Probe)
+			health.GET("/ready", endpoints.Ready, startup.Gate())
 		}
 
 		//Fruits API endpoints /api/fruits
-		fruits := v1.Group("/fruits")
+		fruits := v1.Group("/fruits", startup.Gate(), routes.RequestTimeout(dbTimeout))
 		{
 			fruits.POST("/add", endpoints.AddFruit)
+			fruits.POST("/batch", endpoints.BatchFruits)
 			fruits.GET("/", endpoints.ListFruits)
+			fruits.GET("/trash", endpoints.ListTrash)
+			fruits.GET("/:id", endpoints.GetFruit)
+			fruits.PUT("/:id", endpoints.UpdateFruit)
+			fruits.PATCH("/:id", endpoints.PatchFruit)
 			fruits.DELETE("/:id", endpoints.DeleteFruit)
+			fruits.POST("/:id/restore", endpoints.RestoreFruit)
+			fruits.GET("/:id/history", endpoints.FruitHistory)
+			fruits.POST("/:id/history/:revision/revert", endpoints.RevertFruit)
 			fruits.DELETE("/", endpoints.DeleteAll)
 			fruits.GET("/search/:name", endpoints.GetFruitsByName)
 			fruits.GET("/season/:season", endpoints.GetFruitsBySeason)
 		}
+
+		//the imports and the exports stream their bodies, which could take longer than the dbTimeout
+		transfers := v1.Group("/fruits", startup.Gate(), routes.RequestTimeout(transferTimeout))
+		{
+			transfers.POST("/import", endpoints.ImportFruits)
+			transfers.GET("/export", endpoints.ExportFruits)
+		}
 	}
 
 	router.GET("/swagger/*any", echoSwagger.WrapHandler)